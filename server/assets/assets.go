@@ -0,0 +1,8 @@
+package assets
+
+import "embed"
+
+// Illustrations - built-in illustrations, a directory per illustration type with a manifest.json describing its assets
+//
+//go:embed illustrations
+var Illustrations embed.FS
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru - thread safe least recently used cache bounded by total size of stored entries
+type lru struct {
+	mutex    sync.Mutex
+	maxBytes int64
+	curBytes int64
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry Entry
+}
+
+func newLRU(maxBytes int64) *lru {
+	return &lru{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// get - return entry and mark it as recently used
+func (l *lru) get(key string) (Entry, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if el, ok := l.items[key]; ok {
+		l.order.MoveToFront(el)
+		return el.Value.(*lruItem).entry, true
+	}
+	return Entry{}, false
+}
+
+// add - add entry, evicting least recently used entries when over capacity
+func (l *lru) add(key string, entry Entry) {
+	size := entry.size()
+	if size > l.maxBytes {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if el, ok := l.items[key]; ok {
+		l.curBytes -= el.Value.(*lruItem).entry.size()
+		el.Value.(*lruItem).entry = entry
+		l.curBytes += size
+		l.order.MoveToFront(el)
+	} else {
+		l.items[key] = l.order.PushFront(&lruItem{key: key, entry: entry})
+		l.curBytes += size
+	}
+	for l.curBytes > l.maxBytes {
+		oldest := l.order.Back()
+		if oldest == nil {
+			break
+		}
+		item := l.order.Remove(oldest).(*lruItem)
+		delete(l.items, item.key)
+		l.curBytes -= item.entry.size()
+	}
+}
+
+// len - number of entries in the cache
+func (l *lru) len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.order.Len()
+}
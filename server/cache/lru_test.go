@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"testing"
+)
+
+func TestLRUEviction(t *testing.T) {
+	l := newLRU(30)
+	l.add("a", Entry{Data: []byte("0123456789")})
+	l.add("b", Entry{Data: []byte("0123456789")})
+	l.add("c", Entry{Data: []byte("0123456789")})
+	if l.len() != 3 {
+		t.Errorf("Expected 3 entries but got %d", l.len())
+	}
+	// Touch a so b becomes the oldest
+	if _, ok := l.get("a"); !ok {
+		t.Error("Expected a to be cached")
+	}
+	l.add("d", Entry{Data: []byte("0123456789")})
+	if _, ok := l.get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	for _, k := range []string{"a", "c", "d"} {
+		if _, ok := l.get(k); !ok {
+			t.Errorf("Expected %s to be cached", k)
+		}
+	}
+	// Entries larger than the cache are never stored
+	l.add("e", Entry{Data: make([]byte, 31)})
+	if _, ok := l.get("e"); ok {
+		t.Error("Expected oversized entry to be skipped")
+	}
+}
+
+func TestKey(t *testing.T) {
+	if Key("abc", "png") != Key("abc", "png") {
+		t.Error("Expected key to be deterministic")
+	}
+	if Key("abc", "png") == Key("abc", "webp") {
+		t.Error("Expected different keys for different formats")
+	}
+	if Key("ab", "cpng") == Key("abc", "png") {
+		t.Error("Expected parts to be separated")
+	}
+	if len(Key("abc")) != 64 {
+		t.Errorf("Expected 64 character key but got %d", len(Key("abc")))
+	}
+}
+
+func TestNilRenderCache(t *testing.T) {
+	var rc *RenderCache
+	rc.Set("a", Entry{Data: []byte("a")})
+	if _, ok := rc.Get("a"); ok {
+		t.Error("Expected miss on nil cache")
+	}
+}
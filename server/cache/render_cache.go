@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/appditto/natricon/server/db"
+	"github.com/golang/glog"
+)
+
+// Bump this whenever a change in generation or rendering code alters output for the same parameters
+// Changed illustrations, styles and seasons don't need a bump, keys include a digest of them
+const cacheVersion = "1"
+
+// How long rendered natricons are kept in redis
+const redisTTL = 24 * time.Hour
+
+// Entry - a rendered natricon
+type Entry struct {
+	ContentType string
+	Data        []byte
+}
+
+func (e Entry) size() int64 {
+	return int64(len(e.Data) + len(e.ContentType))
+}
+
+// RenderCache - content addressed cache of rendered natricons
+// Entries are kept in an in-process LRU and optionally in redis, so they can be shared between instances
+type RenderCache struct {
+	lru      *lru
+	useRedis bool
+}
+
+// NewRenderCache - create cache holding up to maxBytes of rendered natricons in memory
+func NewRenderCache(maxBytes int64, useRedis bool) *RenderCache {
+	return &RenderCache{
+		lru:      newLRU(maxBytes),
+		useRedis: useRedis,
+	}
+}
+
+// Key - derive a cache key from every parameter that affects the rendered output
+// The key is a hex digest, suitable for use as an ETag
+func Key(parts ...string) string {
+	hasher := sha256.New()
+	hasher.Write([]byte(cacheVersion))
+	for _, p := range parts {
+		// Length prefix so that parts can't bleed into each other
+		hasher.Write([]byte{byte(len(p) >> 8), byte(len(p))})
+		hasher.Write([]byte(p))
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// Get - retrieve a rendered natricon, checking memory first and redis second
+func (rc *RenderCache) Get(key string) (Entry, bool) {
+	if rc == nil {
+		return Entry{}, false
+	}
+	if entry, ok := rc.lru.get(key); ok {
+		return entry, true
+	}
+	if !rc.useRedis {
+		return Entry{}, false
+	}
+	raw, err := db.GetDB().GetRenderCache(key)
+	if err != nil {
+		return Entry{}, false
+	}
+	// Stored as content type and data separated by a newline
+	sep := bytes.IndexByte(raw, '\n')
+	if sep < 0 {
+		return Entry{}, false
+	}
+	entry := Entry{
+		ContentType: string(raw[:sep]),
+		Data:        raw[sep+1:],
+	}
+	rc.lru.add(key, entry)
+	return entry, true
+}
+
+// Set - store a rendered natricon
+func (rc *RenderCache) Set(key string, entry Entry) {
+	if rc == nil {
+		return
+	}
+	rc.lru.add(key, entry)
+	if rc.useRedis {
+		raw := make([]byte, 0, len(entry.ContentType)+1+len(entry.Data))
+		raw = append(raw, strings.ReplaceAll(entry.ContentType, "\n", "")...)
+		raw = append(raw, '\n')
+		raw = append(raw, entry.Data...)
+		if err := db.GetDB().SetRenderCache(key, raw, redisTTL); err != nil {
+			glog.Errorf("Error saving render cache %s", err)
+		}
+	}
+}
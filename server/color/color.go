@@ -27,7 +27,7 @@ func (c RGB) PerceivedBrightness() float64 {
 
 // Takes a string like '#123456' or 'ABCDEF' and returns an RGB between 0..255
 func HTMLToRGB(in string) (RGB, error) {
-	if in[0] == '#' {
+	if len(in) > 0 && in[0] == '#' {
 		in = in[1:]
 	}
 
@@ -0,0 +1,31 @@
+package color
+
+import "testing"
+
+func TestParseCSS(t *testing.T) {
+	valid := map[string]string{
+		"navy":                "000080",
+		" White ":             "ffffff",
+		"#123456":             "123456",
+		"ABCDEF":              "abcdef",
+		"#f0a":                "ff00aa",
+		"rgb(18, 52, 86)":     "123456",
+		"rgb(100% 0% 50%)":    "ff0080",
+		"hsl(0, 100%, 50%)":   "ff0000",
+		"hsl(360 100% 50%)":   "ff0000",
+		"hsl(240, 100%, 25%)": "000080",
+	}
+	for in, expected := range valid {
+		rgb, err := ParseCSS(in)
+		if err != nil {
+			t.Errorf("Expected %s to be valid, got %v", in, err)
+		} else if rgb.ToHTML(false) != expected {
+			t.Errorf("Expected %s for %s got %s", expected, in, rgb.ToHTML(false))
+		}
+	}
+	for _, in := range []string{"", "#", "notacolor", "#12345", "12345g", "rgb(1, 2)", "rgb(256, 0, 0)", "hsl(0, 101%, 50%)"} {
+		if _, err := ParseCSS(in); err == nil {
+			t.Errorf("Expected %s to be invalid", in)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package color
+
+import "math"
+
+// Lab - CIELAB color with a D65 white point, L between 0..100
+type Lab struct {
+	L, A, B float64
+}
+
+// D65 reference white
+const whiteX = 95.047
+const whiteY = 100.0
+const whiteZ = 108.883
+
+// linearize - convert sRGB channel between 0..255 to linear light between 0..1
+func linearize(c float64) float64 {
+	c /= 255
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// labF - CIELAB companding of a tristimulus value relative to the white point
+func labF(t float64) float64 {
+	if t > 216.0/24389.0 {
+		return math.Cbrt(t)
+	}
+	return (24389.0/27.0*t + 16) / 116
+}
+
+// ToLab - convert sRGB to CIELAB
+func (c RGB) ToLab() Lab {
+	r := linearize(c.R)
+	g := linearize(c.G)
+	b := linearize(c.B)
+
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) * 100
+	y := (0.2126729*r + 0.7151522*g + 0.0721750*b) * 100
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) * 100
+
+	fx := labF(x / whiteX)
+	fy := labF(y / whiteY)
+	fz := labF(z / whiteZ)
+	return Lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+// DeltaE - CIEDE2000 color difference, about 2.3 is the smallest difference people notice
+// See http://www2.ece.rochester.edu/~gsharma/ciede2000/ciede2000noteCRNA.pdf
+func (c Lab) DeltaE(o Lab) float64 {
+	deg := math.Pi / 180
+	c1 := math.Hypot(c.A, c.B)
+	c2 := math.Hypot(o.A, o.B)
+	cBar7 := math.Pow((c1+c2)/2, 7)
+	g := 0.5 * (1 - math.Sqrt(cBar7/(cBar7+math.Pow(25, 7))))
+
+	a1 := (1 + g) * c.A
+	a2 := (1 + g) * o.A
+	c1 = math.Hypot(a1, c.B)
+	c2 = math.Hypot(a2, o.B)
+	h1 := hueAngle(a1, c.B)
+	h2 := hueAngle(a2, o.B)
+
+	dL := o.L - c.L
+	dC := c2 - c1
+	var dh float64
+	if c1*c2 != 0 {
+		dh = h2 - h1
+		if dh > 180 {
+			dh -= 360
+		} else if dh < -180 {
+			dh += 360
+		}
+	}
+	dH := 2 * math.Sqrt(c1*c2) * math.Sin(dh/2*deg)
+
+	lBar := (c.L + o.L) / 2
+	cBar := (c1 + c2) / 2
+	hBar := h1 + h2
+	if c1*c2 != 0 {
+		if math.Abs(h1-h2) <= 180 {
+			hBar /= 2
+		} else if hBar < 360 {
+			hBar = (hBar + 360) / 2
+		} else {
+			hBar = (hBar - 360) / 2
+		}
+	}
+
+	t := 1 - 0.17*math.Cos((hBar-30)*deg) + 0.24*math.Cos(2*hBar*deg) + 0.32*math.Cos((3*hBar+6)*deg) - 0.20*math.Cos((4*hBar-63)*deg)
+	dTheta := 30 * math.Exp(-math.Pow((hBar-275)/25, 2))
+	cBar7 = math.Pow(cBar, 7)
+	rC := 2 * math.Sqrt(cBar7/(cBar7+math.Pow(25, 7)))
+	sL := 1 + 0.015*math.Pow(lBar-50, 2)/math.Sqrt(20+math.Pow(lBar-50, 2))
+	sC := 1 + 0.045*cBar
+	sH := 1 + 0.015*cBar*t
+	rT := -math.Sin(2*dTheta*deg) * rC
+
+	return math.Sqrt(math.Pow(dL/sL, 2) + math.Pow(dC/sC, 2) + math.Pow(dH/sH, 2) + rT*(dC/sC)*(dH/sH))
+}
+
+// hueAngle - hue angle of a and b in degrees between 0..360
+func hueAngle(a float64, b float64) float64 {
+	if a == 0 && b == 0 {
+		return 0
+	}
+	h := math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// Distance - perceptual CIEDE2000 difference between two colors
+func (c RGB) Distance(o RGB) float64 {
+	return c.ToLab().DeltaE(o.ToLab())
+}
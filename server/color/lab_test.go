@@ -0,0 +1,52 @@
+package color
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRGBtoLab(t *testing.T) {
+	tests := []struct {
+		in       RGB
+		expected Lab
+	}{
+		{RGB{0, 0, 0}, Lab{0, 0, 0}},
+		{RGB{255, 255, 255}, Lab{100, 0, 0}},
+		{RGB{255, 0, 0}, Lab{53.2408, 80.0925, 67.2032}},
+		{RGB{0, 0, 255}, Lab{32.2970, 79.1875, -107.8602}},
+	}
+	for _, test := range tests {
+		lab := test.in.ToLab()
+		if math.Abs(lab.L-test.expected.L) > 0.01 || math.Abs(lab.A-test.expected.A) > 0.01 || math.Abs(lab.B-test.expected.B) > 0.01 {
+			t.Errorf("Expected %v for %v got %v", test.expected, test.in, lab)
+		}
+	}
+}
+
+// Pairs from the CIEDE2000 test data of Sharma, Wu and Dalal
+func TestDeltaE(t *testing.T) {
+	tests := []struct {
+		a, b     Lab
+		expected float64
+	}{
+		{Lab{50, 2.6772, -79.7751}, Lab{50, 0, -82.7485}, 2.0425},
+		{Lab{50, 2.5, 0}, Lab{50, 0, -2.5}, 4.3065},
+		{Lab{50, 2.5, 0}, Lab{73, 25, -18}, 27.1492},
+		{Lab{50, 2.5, 0}, Lab{50, 3.1736, 0.5854}, 1.0000},
+		{Lab{60.2574, -34.0099, 36.2677}, Lab{60.4626, -34.1751, 39.4387}, 1.2644},
+		{Lab{22.7233, 20.0904, -46.6940}, Lab{23.0331, 14.9730, -42.5619}, 2.0373},
+		{Lab{90.9257, -0.5406, -0.9208}, Lab{88.6381, -0.8985, -0.7239}, 1.5381},
+		{Lab{2.0776, 0.0795, -1.1350}, Lab{0.9033, -0.0636, -0.5514}, 0.9082},
+	}
+	for _, test := range tests {
+		if d := test.a.DeltaE(test.b); math.Abs(d-test.expected) > 0.0001 {
+			t.Errorf("Expected %.4f between %v and %v got %.4f", test.expected, test.a, test.b, d)
+		}
+		if d := test.b.DeltaE(test.a); math.Abs(d-test.expected) > 0.0001 {
+			t.Errorf("Expected %.4f between %v and %v got %.4f", test.expected, test.b, test.a, d)
+		}
+	}
+	if d := (RGB{12, 34, 56}).Distance(RGB{12, 34, 56}); d != 0 {
+		t.Errorf("Expected no distance between a color and itself got %f", d)
+	}
+}
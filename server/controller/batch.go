@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	goimage "image"
+	"image/draw"
+	"image/png"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/appditto/natricon/server/cache"
+	"github.com/appditto/natricon/server/image"
+	"github.com/appditto/natricon/server/render"
+	"github.com/appditto/natricon/server/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const maxBatchSize = 200               // Maximum number of addresses in a batch request
+const maxSpriteDimension = 4096        // Maximum width and height of sprite sheets
+const maxBatchPixels = 200 * 256 * 256 // Maximum number of pixels a batch request can rasterize
+
+// batchRequest - body of batch requests, options behave like the query parameters of GetNano
+type batchRequest struct {
+	Addresses    []string `json:"addresses"`
+	Format       string   `json:"format"`
+	Size         int      `json:"size"`
+	Quality      int      `json:"quality"`
+	Outline      bool     `json:"outline"`
+	OutlineColor string   `json:"outline_color"`
+	OutlineWidth float64  `json:"outline_width"`
+	Animate      string   `json:"animate"`
+	Background   string   `json:"background"`
+	Shape        string   `json:"shape"`
+	Parts        string   `json:"parts"`
+	Crop         string   `json:"crop"`
+	CSSVars      bool     `json:"css_vars"`
+	Style        string   `json:"style"`
+	Accessory    string   `json:"accessory"`
+	Seasonal     *bool    `json:"seasonal"`
+	Version      int      `json:"v"`
+	Sprite       bool     `json:"sprite"` // Return one sprite sheet instead of an image per address
+	Service      string   `json:"svc"`
+}
+
+// query - get option of the request by its query parameter name
+func (br batchRequest) query(key string) string {
+	switch key {
+	case "format":
+		return br.Format
+	case "size":
+		if br.Size == 0 {
+			return ""
+		}
+		return strconv.Itoa(br.Size)
+	case "quality":
+		if br.Quality == 0 {
+			return ""
+		}
+		return strconv.Itoa(br.Quality)
+	case "outline":
+		return strconv.FormatBool(br.Outline)
+	case "outline_color":
+		return br.OutlineColor
+	case "outline_width":
+		if br.OutlineWidth == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(br.OutlineWidth, 'f', -1, 64)
+	case "animate":
+		return br.Animate
+	case "background":
+		return br.Background
+	case "shape":
+		return br.Shape
+	case "parts":
+		return br.Parts
+	case "crop":
+		return br.Crop
+	case "css_vars":
+		return strconv.FormatBool(br.CSSVars)
+	case "style":
+		return br.Style
+	case "accessory":
+		return br.Accessory
+	case "seasonal":
+		if br.Seasonal == nil {
+			return ""
+		}
+		return strconv.FormatBool(*br.Seasonal)
+	case "v":
+		if br.Version == 0 {
+			return ""
+		}
+		return strconv.Itoa(br.Version)
+	}
+	return ""
+}
+
+// spriteCoordinate - position of a natricon in a sprite sheet
+type spriteCoordinate struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// rasterPixels - most pixels rasterized to render a natricon with the options, 0 for SVGs
+func (opts iconOptions) rasterPixels() int {
+	if opts.format == "svg" {
+		return 0
+	} else if opts.format == "ico" {
+		pixels := 0
+		for _, size := range render.ICOSizes {
+			pixels += int(size * size)
+		}
+		return pixels
+	} else if opts.animation != "" {
+		return opts.size * opts.size * image.MaxPoses
+	}
+	return opts.size * opts.size
+}
+
+// dataURI - encode natricon as a data URI
+func dataURI(entry cache.Entry) string {
+	// Parameters of data URIs aren't separated by spaces
+	mediaType := strings.ReplaceAll(entry.ContentType, " ", "")
+	return fmt.Sprintf("data:%s;base64,%s", mediaType, base64.StdEncoding.EncodeToString(entry.Data))
+}
+
+// Generate natricons for many addresses at once
+func (nc NatriconController) PostBatch(c *gin.Context) {
+	var request batchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.String(http.StatusBadRequest, "Invalid request")
+		return
+	}
+	if len(request.Addresses) == 0 || len(request.Addresses) > maxBatchSize {
+		c.String(http.StatusBadRequest, "addresses must contain between 1 and %d addresses", maxBatchSize)
+		return
+	}
+	opts, err := parseIconOptions(request.query)
+	if err != nil {
+		c.String(http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	// Resolve unique addresses in the order they were requested
+	var addresses []string
+	refs := map[string]natriconRef{}
+	for _, address := range request.Addresses {
+		if !utils.ValidateAddress(address) {
+			c.String(http.StatusBadRequest, "Invalid address %s", address)
+			return
+		}
+		if _, ok := refs[address]; !ok {
+			refs[address] = nc.resolveNatricon(address, "")
+			addresses = append(addresses, address)
+		}
+	}
+
+	if len(addresses)*opts.rasterPixels() > maxBatchPixels {
+		c.String(http.StatusBadRequest, "Batch would rasterize more than %d pixels, request fewer addresses or a smaller size", maxBatchPixels)
+		return
+	}
+
+	// Parse stats without holding up the response
+	clientIP := c.ClientIP()
+	go func() {
+		for _, address := range addresses {
+			*nc.StatsChannel <- &StatsMessage{Address: address, ClientIP: clientIP, Service: request.Service}
+		}
+	}()
+
+	if request.Sprite {
+		nc.generateSprite(c, addresses, refs, opts)
+		return
+	}
+	natricons := map[string]string{}
+	for _, address := range addresses {
+		ref := refs[address]
+		entry, err := nc.getCached(opts.cacheKey(nc.Content, ref.identity(), ref.badgeType, nc.Renderer.Name()), func() (cache.Entry, error) {
+			return nc.renderRef(ref, opts)
+		})
+		if err != nil {
+			c.String(http.StatusInternalServerError, "%s", err.Error())
+			return
+		}
+		natricons[address] = dataURI(entry)
+	}
+	c.JSON(200, gin.H{
+		"natricons": natricons,
+	})
+}
+
+// generateSprite - draw natricons on a grid and respond with the sheet and the position of every address
+func (nc NatriconController) generateSprite(c *gin.Context, addresses []string, refs map[string]natriconRef, opts iconOptions) {
+	if opts.format != "png" && opts.format != "webp" && opts.format != "jpeg" && opts.format != "avif" {
+		c.String(http.StatusBadRequest, "Sprite sheets require format 'png', 'webp', 'jpeg', or 'avif'")
+		return
+	} else if opts.animation != "" {
+		c.String(http.StatusBadRequest, "Sprite sheets can't be animated")
+		return
+	}
+	columns := int(math.Ceil(math.Sqrt(float64(len(addresses)))))
+	rows := (len(addresses) + columns - 1) / columns
+	if columns*opts.size > maxSpriteDimension {
+		c.String(http.StatusBadRequest, "Sprite sheet would be larger than %d pixels, use a smaller size", maxSpriteDimension)
+		return
+	}
+
+	// Tiles are cached as PNGs, like natricons of the single address endpoints, and the sheet by the tiles it's made of
+	tileOpts := opts
+	tileOpts.format = "png"
+	tileOpts.quality = 0
+	var tileKeys []string
+	coordinates := map[string]spriteCoordinate{}
+	for i, address := range addresses {
+		ref := refs[address]
+		tileKeys = append(tileKeys, tileOpts.cacheKey(nc.Content, ref.identity(), ref.badgeType, nc.Renderer.Name()))
+		coordinates[address] = spriteCoordinate{X: (i % columns) * opts.size, Y: (i / columns) * opts.size}
+	}
+	width, height := columns*opts.size, rows*opts.size
+	sheetKey := cache.Key(append([]string{"sprite", opts.format, strconv.Itoa(opts.quality)}, tileKeys...)...)
+	entry, err := nc.getCached(sheetKey, func() (cache.Entry, error) {
+		sheet := goimage.NewRGBA(goimage.Rect(0, 0, width, height))
+		for i, address := range addresses {
+			tile, err := nc.getCached(tileKeys[i], func() (cache.Entry, error) {
+				return nc.renderRef(refs[address], tileOpts)
+			})
+			if err != nil {
+				return cache.Entry{}, err
+			}
+			img, err := png.Decode(bytes.NewReader(tile.Data))
+			if err != nil {
+				return cache.Entry{}, errors.New("Error occured")
+			}
+			coordinate := coordinates[address]
+			draw.Draw(sheet, img.Bounds().Add(goimage.Pt(coordinate.X, coordinate.Y)), img, goimage.Point{}, draw.Src)
+		}
+		encoded, err := render.Encode(sheet, render.ImageFormat(opts.format), opts.quality)
+		if err != nil {
+			return cache.Entry{}, errors.New("Error occured")
+		}
+		return cache.Entry{ContentType: contentType(opts.format), Data: encoded}, nil
+	})
+	if err != nil {
+		c.String(http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+	c.JSON(200, gin.H{
+		"sprite":      dataURI(entry),
+		"size":        opts.size,
+		"width":       width,
+		"height":      height,
+		"coordinates": coordinates,
+	})
+}
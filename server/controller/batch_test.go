@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/appditto/natricon/server/image"
+)
+
+func TestRasterPixels(t *testing.T) {
+	tests := []struct {
+		opts     iconOptions
+		expected int
+	}{
+		{iconOptions{format: "svg", size: 1000}, 0},
+		{iconOptions{format: "png", size: 128}, 128 * 128},
+		{iconOptions{format: "gif", size: 128, animation: "blink"}, 128 * 128 * image.MaxPoses},
+		{iconOptions{format: "ico", size: 1000}, 16*16 + 32*32 + 48*48 + 64*64},
+	}
+	for _, test := range tests {
+		if pixels := test.opts.rasterPixels(); pixels != test.expected {
+			t.Errorf("Expected %d pixels for %+v got %d", test.expected, test.opts, pixels)
+		}
+	}
+	if full := maxBatchSize * (iconOptions{format: "png", size: 256}).rasterPixels(); full > maxBatchPixels {
+		t.Errorf("Expected a full batch at size 256 to fit the budget")
+	}
+	if full := maxBatchSize * (iconOptions{format: "png", size: 1000}).rasterPixels(); full <= maxBatchPixels {
+		t.Errorf("Expected a full batch at size 1000 to exceed the budget")
+	}
+}
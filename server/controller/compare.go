@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/appditto/natricon/server/image"
+	"github.com/appditto/natricon/server/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const maxAddressBookSize = 1000 // Maximum number of addresses an address is compared with at once
+
+// compareRequest - body of bulk compare requests
+type compareRequest struct {
+	Address     string   `json:"address"`
+	AddressBook []string `json:"address_book"`
+	Version     int      `json:"v"`
+}
+
+// compareMatch - similarity of an address book entry to the compared address
+type compareMatch struct {
+	Address string `json:"address"`
+	image.Similarity
+}
+
+// compare - how alike the natricons of two addresses look, the same address is never a lookalike
+func compare(a string, accessoriesA image.Accessories, b string, accessoriesB image.Accessories) image.Similarity {
+	similarity := image.Compare(accessoriesA, accessoriesB)
+	if a == b {
+		similarity.Lookalike = false
+	}
+	return similarity
+}
+
+// sortMatches - most similar first, ties in address book order
+func sortMatches(matches []compareMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+}
+
+// Compare how alike the natricons of two addresses look, so wallets can warn about lookalike addresses
+func (nc NatriconController) GetCompare(c *gin.Context) {
+	a := c.Query("a")
+	b := c.Query("b")
+	if !utils.ValidateAddress(a) || !utils.ValidateAddress(b) {
+		c.String(http.StatusBadRequest, "Invalid address")
+		return
+	}
+	version, err := parseVersion(c.Query("v"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	accessoriesA, err := nc.resolveNatricon(a, "").accessories(iconOptions{version: version})
+	if err != nil {
+		c.String(http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+	accessoriesB, err := nc.resolveNatricon(b, "").accessories(iconOptions{version: version})
+	if err != nil {
+		c.String(http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+	c.JSON(200, gin.H{
+		"a":          a,
+		"b":          b,
+		"version":    version,
+		"similarity": compare(a, accessoriesA, b, accessoriesB),
+	})
+}
+
+// Compare the natricon of an address with every address of an address book, most similar first
+func (nc NatriconController) PostCompare(c *gin.Context) {
+	var request compareRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.String(http.StatusBadRequest, "Invalid request")
+		return
+	}
+	if !utils.ValidateAddress(request.Address) {
+		c.String(http.StatusBadRequest, "Invalid address")
+		return
+	}
+	if len(request.AddressBook) == 0 || len(request.AddressBook) > maxAddressBookSize {
+		c.String(http.StatusBadRequest, "address_book must contain between 1 and %d addresses", maxAddressBookSize)
+		return
+	}
+	for _, address := range request.AddressBook {
+		if !utils.ValidateAddress(address) {
+			c.String(http.StatusBadRequest, "Invalid address %s", address)
+			return
+		}
+	}
+	v := ""
+	if request.Version != 0 {
+		v = strconv.Itoa(request.Version)
+	}
+	version, err := parseVersion(v)
+	if err != nil {
+		c.String(http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	accessories, err := nc.resolveNatricon(request.Address, "").accessories(iconOptions{version: version})
+	if err != nil {
+		c.String(http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+	known := false
+	lookalike := false
+	matches := []compareMatch{}
+	seen := map[string]bool{}
+	for _, address := range request.AddressBook {
+		if address == request.Address {
+			known = true
+			continue
+		} else if seen[address] {
+			continue
+		}
+		seen[address] = true
+		other, err := nc.resolveNatricon(address, "").accessories(iconOptions{version: version})
+		if err != nil {
+			c.String(http.StatusInternalServerError, "%s", err.Error())
+			return
+		}
+		match := compareMatch{Address: address, Similarity: compare(request.Address, accessories, address, other)}
+		lookalike = lookalike || match.Lookalike
+		matches = append(matches, match)
+	}
+	sortMatches(matches)
+	c.JSON(200, gin.H{
+		"address":   request.Address,
+		"version":   version,
+		"known":     known,
+		"lookalike": lookalike,
+		"matches":   matches,
+	})
+}
@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/appditto/natricon/server/color"
+	"github.com/appditto/natricon/server/image"
+	"github.com/appditto/natricon/server/spc"
+)
+
+func TestCompareSameAddress(t *testing.T) {
+	accessories := image.GetSpecificNatricon(spc.BTNone, false, nil, color.HTMLToRGBAlt("#6666ff"), color.HTMLToRGBAlt("#19ffc6"), 5, 15, 8, 10)
+	if s := compare("nano_1a", accessories, "nano_1a", accessories); s.Score != 1 || s.Lookalike {
+		t.Errorf("Expected an address not to be a lookalike of itself but got %v", s)
+	}
+	if s := compare("nano_1a", accessories, "nano_1b", accessories); !s.Lookalike {
+		t.Errorf("Expected another address with the same natricon to be a lookalike but got %v", s)
+	}
+}
+
+func TestSortMatches(t *testing.T) {
+	matches := []compareMatch{
+		{Address: "a", Similarity: image.Similarity{Score: 0.2}},
+		{Address: "b", Similarity: image.Similarity{Score: 0.9}},
+		{Address: "c", Similarity: image.Similarity{Score: 0.2}},
+	}
+	sortMatches(matches)
+	if matches[0].Address != "b" || matches[1].Address != "a" || matches[2].Address != "c" {
+		t.Errorf("Expected b, a, c but got %v", matches)
+	}
+}
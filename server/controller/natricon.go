@@ -1,27 +1,41 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
+	goimage "image"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/appditto/natricon/server/cache"
 	"github.com/appditto/natricon/server/color"
 	"github.com/appditto/natricon/server/db"
 	"github.com/appditto/natricon/server/image"
-	"github.com/appditto/natricon/server/magickwand"
+	"github.com/appditto/natricon/server/render"
 	"github.com/appditto/natricon/server/spc"
 	"github.com/appditto/natricon/server/utils"
 	"github.com/gin-gonic/gin"
 )
 
 const defaultRasterSize = 128 // Default size of PNG/WEBP images
-const minConvertedSize = 100  // Minimum size of PNG/WEBP converted output
+const minConvertedSize = 16   // Minimum size of PNG/WEBP converted output
+const smallRasterSize = 64    // Natricons up to this size are cropped to their bust unless a crop is given
+const minQuality = 1          // Minimum quality of JPEG/AVIF output
+const maxQuality = 100        // Maximum quality of JPEG/AVIF output
 const maxConvertedSize = 1000 // Maximum size of PNG/WEBP converted output
+const cacheMaxAge = 3600      // Seconds clients and CDNs may reuse a natricon before revalidating
+const minOutlineWidth = 1.0   // Minimum outline stroke width, relative to the 512 unit natricon
+const maxOutlineWidth = 48.0  // Maximum outline stroke width, relative to the 512 unit natricon
+const autoBackground = "auto" // Background derived from the body color
 
 type NatriconController struct {
 	Seed         string
-	StatsChannel *chan *gin.Context
+	StatsChannel *chan *StatsMessage
+	Renderer     render.Renderer
+	Cache        *cache.RenderCache
+	Content      string // Digest of the loaded illustrations, styles and seasons, part of every cache key and ETag
 }
 
 // APIs
@@ -44,57 +58,209 @@ func (nc NatriconController) GetNonce(c *gin.Context) {
 	})
 }
 
-// Generate natricon with given nano address
-func (nc NatriconController) GetNano(c *gin.Context) {
-	address := c.Query("address")
-	nonce, err := strconv.Atoi(c.Query("nonce"))
+// natriconRef - what an address resolves to, either a hash or a vanity with fixed assets
+type natriconRef struct {
+	pubKey    string
+	hash      string
+	nonce     int // Applied nonce, db.NoNonceApplied if none
+	badgeType spc.BadgeType
+	vanity    *spc.Vanity
+	special   bool // Whether the vanity specifies all assets and colors
+}
+
+// resolveNatricon - resolve address to the natricon it is shown with
+// nonceParam is the nonce query parameter, -1 disables nonces and empty uses the stored nonce
+func (nc NatriconController) resolveNatricon(address string, nonceParam string) natriconRef {
+	nonce, err := strconv.Atoi(nonceParam)
 	if err != nil {
 		nonce = db.NoNonceApplied
 	}
-	valid := utils.ValidateAddress(address)
-	if !valid {
-		c.String(http.StatusBadRequest, "Invalid address")
-		return
-	}
-
-	// Parse stats
-	*nc.StatsChannel <- c
-
-	var sha256 string
-	var badgeType spc.BadgeType
-	specialNatricon := false
-	pubKey := utils.AddressToPub(address)
-	vanity := spc.Vanities[pubKey]
-	if vanity == nil {
-		badgeType = image.GetBadgeSvc().GetBadgeType(pubKey)
+	ref := natriconRef{pubKey: utils.AddressToPub(address), nonce: db.NoNonceApplied}
+	ref.vanity = spc.Vanities[ref.pubKey]
+	if ref.vanity == nil {
+		hashKey := ref.pubKey
+		ref.badgeType = image.GetBadgeSvc().GetBadgeType(ref.pubKey)
 		if nonce != -1 {
 			if nonce == db.NoNonceApplied {
-				nonce = db.GetDB().GetNonce(pubKey)
+				nonce = db.GetDB().GetNonce(ref.pubKey)
 			}
 			if nonce != db.NoNonceApplied {
-				pubKey = fmt.Sprintf("%s:%s", strconv.Itoa(nonce), pubKey)
+				ref.nonce = nonce
+				hashKey = fmt.Sprintf("%s:%s", strconv.Itoa(nonce), ref.pubKey)
 			}
 		}
-		sha256 = utils.PKSha256(pubKey, nc.Seed)
+		ref.hash = utils.PKSha256(hashKey, nc.Seed)
 	} else {
-		badgeType = vanity.Badge
-		if badgeType == "" {
-			badgeType = spc.BTNone
+		ref.badgeType = ref.vanity.Badge
+		if ref.badgeType == "" {
+			ref.badgeType = spc.BTNone
 		}
-		if vanity.BodyAssetID > 0 && vanity.HairAssetID > 0 && vanity.EyeAssetID > 0 && vanity.BodyColor != nil && vanity.HairColor != nil {
-			specialNatricon = true
-		} else if vanity.Hash == "" {
-			sha256 = utils.PKSha256(pubKey, nc.Seed)
+		if ref.vanity.BodyAssetID > 0 && ref.vanity.HairAssetID > 0 && ref.vanity.EyeAssetID > 0 && ref.vanity.BodyColor != nil && ref.vanity.HairColor != nil {
+			ref.special = true
+		} else if ref.vanity.Hash == "" {
+			ref.hash = utils.PKSha256(ref.pubKey, nc.Seed)
 		} else {
-			sha256 = vanity.Hash
+			ref.hash = ref.vanity.Hash
 		}
 	}
+	return ref
+}
 
-	if specialNatricon {
-		generateSpecialIcon(vanity, badgeType, c)
+// identity - what the natricon is generated from, used for cache keys
+func (ref natriconRef) identity() string {
+	if ref.special {
+		return fmt.Sprintf("vanity:%s", ref.pubKey)
+	} else if ref.vanity != nil && len(ref.vanity.Accessories) > 0 {
+		return fmt.Sprintf("%s:%s", ref.hash, strings.Join(ref.vanity.Accessories, ","))
+	}
+	return ref.hash
+}
+
+// accessoryNames - accessories worn with the accessory option, auto picks them with a version of the algorithm
+// Vanities wear their own accessories by default, other natricons wear none
+func (ref natriconRef) accessoryNames(accessory string, v image.Version) []string {
+	switch accessory {
+	case "":
+		if ref.vanity != nil {
+			return ref.vanity.Accessories
+		}
+		return nil
+	case image.AccessoryNone:
+		return nil
+	case image.AccessoryAuto:
+		if ref.special {
+			return image.PickAccessories(ref.pubKey, v)
+		}
+		return image.PickAccessories(ref.hash, v)
+	}
+	return strings.Split(accessory, ",")
+}
+
+// accessories - get accessories of the natricon rendered with given options
+func (ref natriconRef) accessories(opts iconOptions) (image.Accessories, error) {
+	var accessories image.Accessories
+	if ref.special {
+		v := ref.vanity
+		accessories = image.GetSpecificNatricon(ref.badgeType, opts.outline, opts.outlineColor, v.BodyColor, v.HairColor, v.BodyAssetID, v.HairAssetID, v.MouthAssetID, v.EyeAssetID)
 	} else {
-		generateIcon(&sha256, badgeType, c)
+		var err error
+		accessories, err = image.GetAccessoriesForHashVersion(ref.hash, opts.version, ref.badgeType, opts.outline, opts.outlineColor)
+		if err != nil {
+			return accessories, err
+		}
+	}
+	accessories.OutlineWidth = opts.outlineWidth
+	accessories.Shape = opts.shape
+	accessories.Parts = opts.parts
+	accessories.Crop = opts.crop
+	accessories.CSSVars = opts.cssVars
+	if err := accessories.Wear(ref.accessoryNames(opts.accessory, opts.version)); err != nil {
+		return accessories, err
+	}
+	if opts.season != "" {
+		accessories.WearSeason(image.GetSeason(opts.season))
+	}
+	accessories = accessories.WithStyle(opts.style)
+	if opts.background == autoBackground {
+		background := image.AutoBackground(accessories.BodyColor)
+		accessories.Background = &background
+	} else if opts.background != "" {
+		accessories.Background = color.HTMLToRGBAlt(opts.background)
+	}
+	return accessories, nil
+}
+
+// Generate natricon with given nano address
+func (nc NatriconController) GetNano(c *gin.Context) {
+	address := c.Query("address")
+	valid := utils.ValidateAddress(address)
+	if !valid {
+		c.String(http.StatusBadRequest, "Invalid address")
+		return
+	}
+
+	// Parse stats
+	*nc.StatsChannel <- &StatsMessage{Address: address, ClientIP: c.ClientIP(), Service: c.Query("svc")}
+
+	nc.generateIcon(nc.resolveNatricon(address, c.Query("nonce")), c, negotiatedQuery(c))
+}
+
+// Get traits of the natricon for a nano address
+func (nc NatriconController) GetTraits(c *gin.Context) {
+	address := c.Query("address")
+	valid := utils.ValidateAddress(address)
+	if !valid {
+		c.String(http.StatusBadRequest, "Invalid address")
+		return
+	}
+
+	version, err := parseVersion(c.Query("v"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	ref := nc.resolveNatricon(address, c.Query("nonce"))
+	accessories, err := ref.accessories(iconOptions{version: version})
+	if err != nil {
+		c.String(http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+	nonce := ref.nonce
+	if nonce == db.NoNonceApplied {
+		nonce = -1
+	}
+	var badge *spc.BadgeType
+	if ref.badgeType != spc.BTNone {
+		badge = &ref.badgeType
+	}
+	c.JSON(200, gin.H{
+		"address": address,
+		"traits":  image.GetTraits(accessories),
+		"badge":   badge,
+		"nonce":   nonce,
+		"vanity":  ref.vanity != nil,
+		"version": version,
+	})
+}
+
+// Get how rare the natricon of a nano address is
+func (nc NatriconController) GetRarity(c *gin.Context) {
+	address := c.Query("address")
+	valid := utils.ValidateAddress(address)
+	if !valid {
+		c.String(http.StatusBadRequest, "Invalid address")
+		return
+	}
+
+	version, err := parseVersion(c.Query("v"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	table, err := image.GetRarityTable(version)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+	ref := nc.resolveNatricon(address, c.Query("nonce"))
+	accessories, err := ref.accessories(iconOptions{version: version})
+	if err != nil {
+		c.String(http.StatusInternalServerError, "%s", err.Error())
+		return
 	}
+	nonce := ref.nonce
+	if nonce == db.NoNonceApplied {
+		nonce = -1
+	}
+	c.JSON(200, gin.H{
+		"address": address,
+		"rarity":  table.Rarity(accessories),
+		"nonce":   nonce,
+		"vanity":  ref.vanity != nil,
+		"version": version,
+	})
 }
 
 // Testing APIs
@@ -217,122 +383,408 @@ func (nc NatriconController) GetNatricon(c *gin.Context) {
 	})
 }
 
-// Generate natricon with given hash
-func generateIcon(hash *string, badgeType spc.BadgeType, c *gin.Context) {
+// iconOptions - options that affect the rendered output of a natricon
+type iconOptions struct {
+	format       string
+	size         int
+	quality      int // Quality of lossy formats, 0 for the default of the format
+	outline      bool
+	outlineColor *color.RGB
+	outlineWidth float64
+	animation    image.Animation
+	background   string // Hex color, autoBackground or empty for transparent
+	shape        image.Shape
+	parts        []image.Part // Every part when nil
+	crop         image.Crop
+	cssVars      bool
+	style        image.Style
+	accessory    string // Accessory names in layer order, image.AccessoryAuto or image.AccessoryNone, empty for the default
+	season       string // Name of the seasonal overlays drawn, empty for none
+	seasonal     bool   // Whether seasonal overlays are drawn when a season is active
+	version      image.Version
+}
+
+// parseVersion - parse version of the generation algorithm, image.DefaultVersion when empty
+func parseVersion(v string) (image.Version, error) {
+	if v == "" {
+		return image.DefaultVersion, nil
+	}
+	version, ok := image.ParseVersion(v)
+	if !ok {
+		var names []string
+		for _, v := range image.Versions {
+			names = append(names, fmt.Sprintf("'%d'", v))
+		}
+		return version, fmt.Errorf("Valid versions are %s", strings.Join(names, ", "))
+	}
+	return version, nil
+}
+
+// parseIconOptions - parse and validate rendering options, query returns the value of an option
+func parseIconOptions(query func(key string) string) (iconOptions, error) {
 	var err error
+	opts := iconOptions{}
 
-	format := strings.ToLower(c.Query("format"))
-	size := 0
-	if format == "" || format == "svg" {
-		format = "svg"
-	} else if format != "png" && format != "webp" {
-		c.String(http.StatusBadRequest, "%s", "Valid formats are 'svg', 'png', or 'webp'")
-		return
+	opts.format = strings.ToLower(query("format"))
+	if opts.format == "jpg" {
+		opts.format = "jpeg"
+	}
+	if opts.format == "" || opts.format == "svg" {
+		opts.format = "svg"
+	} else if opts.format == "ico" {
+		// ICOs contain every size in render.ICOSizes
+	} else if opts.format != "png" && opts.format != "webp" && opts.format != "gif" && opts.format != "apng" && opts.format != "jpeg" && opts.format != "avif" {
+		return opts, errors.New("Valid formats are 'svg', 'png', 'webp', 'gif', 'apng', 'ico', 'jpeg', or 'avif'")
 	} else {
-		sizeStr := c.Query("size")
+		sizeStr := query("size")
 		if sizeStr == "" {
-			size = defaultRasterSize
+			opts.size = defaultRasterSize
 		} else {
-			size, err = strconv.Atoi(c.Query("size"))
-			if err != nil || size < minConvertedSize || size > maxConvertedSize {
-				c.String(http.StatusBadRequest, "%s", fmt.Sprintf("size must be an integer between %d and %d", minConvertedSize, maxConvertedSize))
-				return
+			opts.size, err = strconv.Atoi(sizeStr)
+			if err != nil || opts.size < minConvertedSize || opts.size > maxConvertedSize {
+				return opts, fmt.Errorf("size must be an integer between %d and %d", minConvertedSize, maxConvertedSize)
 			}
 		}
 	}
 
-	outline := strings.ToLower(c.Query("outline")) == "true"
-	// Get outline and outline color info, black is default
-	var outlineColor *color.RGB
-	if outline {
-		if strings.ToLower(c.Query("outline_color")) == "black" {
-			outlineColor = &color.RGB{R: 0.0, G: 0.0, B: 0.0}
-		} else {
-			outlineColor = &color.RGB{R: 255.0, G: 255.0, B: 255.0}
+	if qualityStr := query("quality"); qualityStr != "" {
+		if !render.ImageFormat(opts.format).Lossy() {
+			return opts, errors.New("quality requires format 'jpeg' or 'avif'")
+		}
+		opts.quality, err = strconv.Atoi(qualityStr)
+		if err != nil || opts.quality < minQuality || opts.quality > maxQuality {
+			return opts, fmt.Errorf("quality must be an integer between %d and %d", minQuality, maxQuality)
+		}
+	}
+
+	if animate := query("animate"); animate != "" {
+		var ok bool
+		opts.animation, ok = image.ParseAnimation(animate)
+		if !ok {
+			var names []string
+			for _, a := range image.Animations {
+				names = append(names, fmt.Sprintf("'%s'", a))
+			}
+			return opts, fmt.Errorf("Valid animations are %s", strings.Join(names, ", "))
+		} else if opts.format != "svg" && opts.format != "gif" && opts.format != "apng" && opts.format != "webp" {
+			return opts, errors.New("Animations require format 'svg', 'gif', 'apng', or 'webp'")
+		}
+	}
+
+	opts.outline = strings.ToLower(query("outline")) == "true"
+	// Get outline color and width, white is default
+	if opts.outline {
+		outlineColor := color.RGB{R: 255.0, G: 255.0, B: 255.0}
+		if colorStr := query("outline_color"); colorStr != "" {
+			outlineColor, err = color.ParseCSS(colorStr)
+			if err != nil {
+				return opts, errors.New("outline_color must be a hex or CSS color")
+			}
+		}
+		opts.outlineColor = &outlineColor
+		opts.outlineWidth = image.DefaultOutlineWidth
+		if widthStr := query("outline_width"); widthStr != "" {
+			opts.outlineWidth, err = strconv.ParseFloat(widthStr, 64)
+			if err != nil || opts.outlineWidth < minOutlineWidth || opts.outlineWidth > maxOutlineWidth {
+				return opts, fmt.Errorf("outline_width must be a number between %g and %g", minOutlineWidth, maxOutlineWidth)
+			}
+		}
+	}
+
+	switch background := strings.ToLower(query("background")); background {
+	case "", "none":
+	case autoBackground:
+		opts.background = autoBackground
+	default:
+		backgroundColor, err := color.ParseCSS(background)
+		if err != nil {
+			return opts, errors.New("background must be a hex or CSS color, 'auto', or 'none'")
+		}
+		opts.background = backgroundColor.ToHTML(false)
+	}
+
+	opts.shape = image.Square
+	if shape := query("shape"); shape != "" {
+		var ok bool
+		opts.shape, ok = image.ParseShape(shape)
+		if !ok {
+			var names []string
+			for _, s := range image.Shapes {
+				names = append(names, fmt.Sprintf("'%s'", s))
+			}
+			return opts, fmt.Errorf("Valid shapes are %s", strings.Join(names, ", "))
 		}
 	}
 
-	accessories, err := image.GetAccessoriesForHash(*hash, badgeType, outline, outlineColor)
+	opts.parts, err = image.ParseParts(query("parts"))
 	if err != nil {
-		c.String(http.StatusInternalServerError, "%s", err.Error())
+		var names []string
+		for _, p := range image.Parts {
+			names = append(names, fmt.Sprintf("'%s'", p))
+		}
+		return opts, fmt.Errorf("%s, valid parts are %s", err.Error(), strings.Join(names, ", "))
+	}
+
+	opts.crop = image.CropFull
+	if opts.format == "ico" || (opts.size > 0 && opts.size <= smallRasterSize) {
+		// Most of the canvas is empty space, which small icons can't spare
+		opts.crop = image.CropBust
+	}
+	if crop := query("crop"); crop != "" {
+		var ok bool
+		opts.crop, ok = image.ParseCrop(crop)
+		if !ok {
+			var names []string
+			for _, c := range image.Crops {
+				names = append(names, fmt.Sprintf("'%s'", c))
+			}
+			return opts, fmt.Errorf("Valid crops are %s", strings.Join(names, ", "))
+		}
+	}
+
+	opts.style = image.DefaultStyle
+	if style := query("style"); style != "" {
+		var ok bool
+		opts.style, ok = image.ParseStyle(style)
+		if !ok {
+			var names []string
+			for _, s := range image.Styles() {
+				names = append(names, fmt.Sprintf("'%s'", s))
+			}
+			return opts, fmt.Errorf("Valid styles are %s", strings.Join(names, ", "))
+		}
+	}
+
+	switch accessory := strings.ToLower(strings.TrimSpace(query("accessory"))); accessory {
+	case "", image.AccessoryAuto, image.AccessoryNone:
+		opts.accessory = accessory
+	default:
+		names, err := image.ParseAccessories(accessory)
+		if err != nil {
+			return opts, fmt.Errorf("%s, valid accessories are '%s', '%s', '%s'", err.Error(), image.AccessoryAuto, image.AccessoryNone, strings.Join(image.AccessoryNames(), "', '"))
+		}
+		opts.accessory = strings.Join(names, ",")
+		if opts.accessory == "" {
+			opts.accessory = image.AccessoryNone
+		}
+	}
+
+	// Seasonal overlays are drawn unless apps opt out
+	opts.seasonal = strings.ToLower(query("seasonal")) != "false"
+	if opts.seasonal {
+		if season := image.ActiveSeason(time.Now()); season != nil {
+			opts.season = season.Name
+		}
+	}
+
+	opts.cssVars = strings.ToLower(query("css_vars")) == "true"
+	if opts.cssVars && opts.format != "svg" {
+		return opts, errors.New("CSS variables require format 'svg'")
+	}
+
+	if opts.version, err = parseVersion(query("v")); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// cacheKey - content address of a natricon rendered with these options
+// content is the digest of the loaded assets and identity the hash or vanity the natricon is generated from
+func (opts iconOptions) cacheKey(content string, identity string, badgeType spc.BadgeType, renderer string) string {
+	outlineColor := ""
+	if opts.outlineColor != nil {
+		outlineColor = opts.outlineColor.ToHTML(false)
+	}
+	if opts.format == "svg" {
+		// SVGs don't go through the renderer
+		renderer = ""
+	}
+	return cache.Key(
+		content,
+		identity,
+		string(badgeType),
+		strconv.FormatBool(opts.outline),
+		outlineColor,
+		strconv.FormatFloat(opts.outlineWidth, 'f', -1, 64),
+		opts.background,
+		string(opts.shape),
+		partsKey(opts.parts),
+		string(opts.crop),
+		opts.format,
+		strconv.Itoa(opts.size),
+		strconv.Itoa(opts.quality),
+		string(opts.animation),
+		strconv.FormatBool(opts.cssVars),
+		string(opts.style),
+		opts.accessory,
+		opts.season,
+		strconv.Itoa(int(opts.version)),
+		renderer,
+	)
+}
+
+// partsKey - identify selected parts in cache keys
+func partsKey(parts []image.Part) string {
+	if parts == nil {
+		return ""
+	}
+	names := make([]string, len(parts))
+	for i, p := range parts {
+		names[i] = string(p)
+	}
+	// Distinguish an empty selection from every part
+	return "parts:" + strings.Join(names, ",")
+}
+
+// maxAge - seconds clients and CDNs may reuse a natricon, at most until the seasonal overlays drawn on it change
+func (opts iconOptions) maxAge(now time.Time) int {
+	if opts.seasonal {
+		if change, ok := image.NextSeasonChange(now); ok && change.Sub(now) < cacheMaxAge*time.Second {
+			return int(change.Sub(now) / time.Second)
+		}
+	}
+	return cacheMaxAge
+}
+
+// serveCached - respond with a natricon from the render cache, generating it on a miss
+func (nc NatriconController) serveCached(c *gin.Context, key string, maxAge int, generate func() (cache.Entry, error)) {
+	etag := fmt.Sprintf("\"%s\"", key)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
 		return
 	}
-	bodyHsv := accessories.BodyColor.ToHSB()
-	hairHsv := accessories.HairColor.ToHSB()
-	deltaHsv := color.HSB{}
-	deltaHsv.H = hairHsv.H - bodyHsv.H
-	deltaHsv.S = hairHsv.S - bodyHsv.S
-	deltaHsv.B = hairHsv.B - bodyHsv.B
-	svg, err := image.CombineSVG(accessories)
+	entry, err := nc.getCached(key, generate)
 	if err != nil {
-		c.String(http.StatusInternalServerError, "Error occured")
+		c.Header("ETag", "")
+		c.Header("Cache-Control", "no-store")
+		c.String(http.StatusInternalServerError, "%s", err.Error())
 		return
 	}
-	if format != "svg" {
-		// Convert
-		var converted []byte
-		converted, err = magickwand.ConvertSvgToBinary(svg, magickwand.ImageFormat(format), uint(size))
-		if err != nil {
-			c.String(http.StatusInternalServerError, "Error occured")
-			return
+	c.Data(200, entry.ContentType, entry.Data)
+}
+
+// getCached - get entry from the render cache, generating and storing it on a miss
+func (nc NatriconController) getCached(key string, generate func() (cache.Entry, error)) (cache.Entry, error) {
+	if entry, ok := nc.Cache.Get(key); ok {
+		return entry, nil
+	}
+	entry, err := generate()
+	if err != nil {
+		return cache.Entry{}, err
+	}
+	nc.Cache.Set(key, entry)
+	return entry, nil
+}
+
+// etagMatches - check If-None-Match header against an ETag
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
 		}
-		c.Data(200, fmt.Sprintf("image/%s", format), converted)
-		return
 	}
-	c.Data(200, "image/svg+xml; charset=utf-8", svg)
+	return false
 }
 
-// Generate icon for special accounts
-func generateSpecialIcon(vanity *spc.Vanity, badgeType spc.BadgeType, c *gin.Context) {
-	var err error
+// contentType - MIME type of natricons in the given format
+func contentType(format string) string {
+	if format == "svg" {
+		return "image/svg+xml; charset=utf-8"
+	} else if format == "ico" {
+		return "image/x-icon"
+	}
+	return fmt.Sprintf("image/%s", format)
+}
 
-	format := strings.ToLower(c.Query("format"))
-	size := 0
-	if format == "" || format == "svg" {
-		format = "svg"
-	} else if format != "png" && format != "webp" {
-		c.String(http.StatusBadRequest, "%s", "Valid formats are 'svg', 'png', or 'webp'")
-		return
-	} else {
-		sizeStr := c.Query("size")
-		if sizeStr == "" {
-			size = defaultRasterSize
-		} else {
-			size, err = strconv.Atoi(c.Query("size"))
-			if err != nil || size < minConvertedSize || size > maxConvertedSize {
-				c.String(http.StatusBadRequest, "%s", fmt.Sprintf("size must be an integer between %d and %d", minConvertedSize, maxConvertedSize))
-				return
+// renderIcon - create natricon in the requested format, animating it if requested
+func (nc NatriconController) renderIcon(accessories image.Accessories, opts iconOptions) (cache.Entry, error) {
+	isAnimation := opts.animation != "" || opts.format == "gif" || opts.format == "apng"
+	if !isAnimation {
+		svg, err := image.CombineSVG(accessories)
+		if err != nil {
+			return cache.Entry{}, errors.New("Error occured")
+		}
+		if opts.format == "ico" {
+			images := make([]*goimage.RGBA, len(render.ICOSizes))
+			for i, size := range render.ICOSizes {
+				images[i], err = nc.Renderer.Rasterize(svg, size)
+				if err != nil {
+					return cache.Entry{}, errors.New("Error occured")
+				}
+			}
+			svg, err = render.EncodeICO(images)
+			if err != nil {
+				return cache.Entry{}, errors.New("Error occured")
+			}
+		} else if opts.format != "svg" {
+			svg, err = nc.Renderer.Convert(svg, render.ImageFormat(opts.format), uint(opts.size), opts.quality)
+			if err != nil {
+				return cache.Entry{}, errors.New("Error occured")
 			}
 		}
+		return cache.Entry{ContentType: contentType(opts.format), Data: svg}, nil
 	}
 
-	outline := strings.ToLower(c.Query("outline")) == "true"
-	// Get outline and outline color info, black is default
-	var outlineColor *color.RGB
-	if outline {
-		if strings.ToLower(c.Query("outline_color")) == "black" {
-			outlineColor = &color.RGB{R: 0.0, G: 0.0, B: 0.0}
-		} else {
-			outlineColor = &color.RGB{R: 255.0, G: 255.0, B: 255.0}
+	// GIF and APNG without an animation are a single still frame
+	frames := []image.Frame{{}}
+	if opts.animation != "" {
+		frames = image.GetAnimationFrames(accessories, opts.animation)
+	}
+	if opts.format == "svg" {
+		svg, err := image.CombineAnimatedSVG(accessories, frames)
+		if err != nil {
+			return cache.Entry{}, errors.New("Error occured")
+		}
+		return cache.Entry{ContentType: contentType(opts.format), Data: svg}, nil
+	}
+	// Rasterize every distinct pose once
+	rasterized := map[image.Pose]*goimage.RGBA{}
+	images := make([]*goimage.RGBA, len(frames))
+	delays := make([]int, len(frames))
+	for i, frame := range frames {
+		img, ok := rasterized[frame.Pose]
+		if !ok {
+			svg, err := image.CombineFrameSVG(accessories, frame.Pose)
+			if err != nil {
+				return cache.Entry{}, errors.New("Error occured")
+			}
+			img, err = nc.Renderer.Rasterize(svg, uint(opts.size))
+			if err != nil {
+				return cache.Entry{}, errors.New("Error occured")
+			}
+			rasterized[frame.Pose] = img
 		}
+		images[i] = img
+		delays[i] = frame.Delay
+	}
+	converted, err := render.EncodeAnimation(images, delays, render.ImageFormat(opts.format))
+	if err != nil {
+		return cache.Entry{}, errors.New("Error occured")
 	}
+	return cache.Entry{ContentType: contentType(opts.format), Data: converted}, nil
+}
 
-	accessories := image.GetSpecificNatricon(badgeType, outline, outlineColor, vanity.BodyColor, vanity.HairColor, vanity.BodyAssetID, vanity.HairAssetID, vanity.MouthAssetID, vanity.EyeAssetID)
-	svg, err := image.CombineSVG(accessories)
+// Generate natricon for a resolved address
+func (nc NatriconController) generateIcon(ref natriconRef, c *gin.Context, query func(key string) string) {
+	opts, err := parseIconOptions(query)
 	if err != nil {
-		c.String(http.StatusInternalServerError, "Error occured")
+		c.String(http.StatusBadRequest, "%s", err.Error())
 		return
 	}
-	if format != "svg" {
-		// Convert
-		var converted []byte
-		converted, err = magickwand.ConvertSvgToBinary(svg, magickwand.ImageFormat(format), uint(size))
-		if err != nil {
-			c.String(http.StatusInternalServerError, "Error occured")
-			return
-		}
-		c.Data(200, fmt.Sprintf("image/%s", format), converted)
-		return
+
+	nc.serveCached(c, opts.cacheKey(nc.Content, ref.identity(), ref.badgeType, nc.Renderer.Name()), opts.maxAge(time.Now()), func() (cache.Entry, error) {
+		return nc.renderRef(ref, opts)
+	})
+}
+
+// renderRef - render the natricon of a resolved address
+func (nc NatriconController) renderRef(ref natriconRef, opts iconOptions) (cache.Entry, error) {
+	accessories, err := ref.accessories(opts)
+	if err != nil {
+		return cache.Entry{}, err
 	}
-	c.Data(200, "image/svg+xml; charset=utf-8", svg)
+	return nc.renderIcon(accessories, opts)
 }
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/appditto/natricon/server/image"
+	"github.com/appditto/natricon/server/spc"
+)
+
+func TestCacheKeyContent(t *testing.T) {
+	query := map[string]string{"format": "png"}
+	opts, err := parseIconOptions(func(key string) string { return query[key] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := opts.cacheKey("assets-a", "hash", spc.BTNone, "native")
+	if key != opts.cacheKey("assets-a", "hash", spc.BTNone, "native") {
+		t.Errorf("Expected the same key for the same assets")
+	}
+	if key == opts.cacheKey("assets-b", "hash", spc.BTNone, "native") {
+		t.Errorf("Expected changed assets to change the key")
+	}
+}
+
+func TestMaxAgeSeasonChange(t *testing.T) {
+	config := path.Join(t.TempDir(), "seasons.json")
+	if err := ioutil.WriteFile(config, []byte(`{"seasons": [{"name": "holidays", "start": "12-20", "end": "01-05", "overlays": ["santa-hat"]}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := image.LoadSeasons(config); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		ioutil.WriteFile(config, []byte(`{"seasons": []}`), 0644)
+		image.LoadSeasons(config)
+	}()
+
+	// Holidays start half an hour later, caches must not keep natricons without the overlays past then
+	now := time.Date(2026, 12, 19, 23, 30, 0, 0, time.UTC)
+	if maxAge := (iconOptions{seasonal: true}).maxAge(now); maxAge != 1800 {
+		t.Errorf("Expected max-age until the season starts got %d", maxAge)
+	}
+	if maxAge := (iconOptions{}).maxAge(now); maxAge != cacheMaxAge {
+		t.Errorf("Expected full max-age without seasonal overlays got %d", maxAge)
+	}
+	if maxAge := (iconOptions{seasonal: true}).maxAge(now.Add(-12 * time.Hour)); maxAge != cacheMaxAge {
+		t.Errorf("Expected full max-age far from a change of season got %d", maxAge)
+	}
+}
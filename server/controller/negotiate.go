@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Formats that can be negotiated, in order of preference when clients accept several equally
+var negotiableFormats = []struct {
+	format    string
+	mediaType string
+}{
+	{"svg", "image/svg+xml"},
+	{"avif", "image/avif"},
+	{"webp", "image/webp"},
+	{"png", "image/png"},
+}
+
+// Client hints that affect the size of raster natricons
+var sizeHints = []string{"DPR", "Width", "Sec-CH-DPR", "Sec-CH-Width"}
+
+// acceptQuality - quality of a media type in an Accept header
+// exact is false when the media type is only accepted through a wildcard
+func acceptQuality(accept string, mediaType string) (q float64, exact bool) {
+	q = -1
+	specificity := -1
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		accepted := strings.ToLower(strings.TrimSpace(params[0]))
+		var s int
+		switch {
+		case accepted == mediaType:
+			s = 2
+		case accepted == mediaType[:strings.Index(mediaType, "/")]+"/*":
+			s = 1
+		case accepted == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		// The most specific match decides the quality
+		if s < specificity {
+			continue
+		}
+		specificity = s
+		q = 1
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+	}
+	return q, specificity == 2
+}
+
+// negotiateFormat - best format for an Accept header, svg when nothing is preferred
+// Formats named by the client win over formats that are only accepted through wildcards
+func negotiateFormat(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return "svg"
+	}
+	best, bestQ, bestExact := "svg", 0.0, false
+	for _, f := range negotiableFormats {
+		q, exact := acceptQuality(accept, f.mediaType)
+		if q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && exact && !bestExact) {
+			best, bestQ, bestExact = f.format, q, exact
+		}
+	}
+	return best
+}
+
+// hintedSize - size of raster natricons requested with client hints, 0 when there are none
+// Width is in physical pixels already, DPR scales the default size
+func hintedSize(header http.Header) int {
+	for _, name := range []string{"Sec-CH-Width", "Width"} {
+		if width, err := strconv.Atoi(strings.TrimSpace(header.Get(name))); err == nil && width > 0 {
+			return clampSize(width)
+		}
+	}
+	for _, name := range []string{"Sec-CH-DPR", "DPR"} {
+		if dpr, err := strconv.ParseFloat(strings.TrimSpace(header.Get(name)), 64); err == nil && dpr > 0 {
+			return clampSize(int(math.Round(defaultRasterSize * dpr)))
+		}
+	}
+	return 0
+}
+
+func clampSize(size int) int {
+	if size < minConvertedSize {
+		return minConvertedSize
+	} else if size > maxConvertedSize {
+		return maxConvertedSize
+	}
+	return size
+}
+
+// negotiatedQuery - query parameters of a request, with format and size taken from headers when they are missing
+// Sets Vary and Accept-CH so caches and browsers know which headers affect the response
+func negotiatedQuery(c *gin.Context) func(key string) string {
+	formatMissing := c.Query("format") == ""
+	// SVGs have no size
+	sizeMissing := c.Query("size") == "" && strings.ToLower(c.Query("format")) != "svg"
+	var vary []string
+	if formatMissing {
+		vary = append(vary, "Accept")
+	}
+	if sizeMissing {
+		c.Header("Accept-CH", strings.Join(sizeHints, ", "))
+		vary = append(vary, sizeHints...)
+	}
+	if len(vary) > 0 {
+		c.Header("Vary", strings.Join(vary, ", "))
+	}
+	return func(key string) string {
+		switch {
+		case key == "format" && formatMissing:
+			return negotiateFormat(c.GetHeader("Accept"))
+		case key == "size" && sizeMissing:
+			if size := hintedSize(c.Request.Header); size > 0 {
+				return strconv.Itoa(size)
+			}
+		}
+		return c.Query(key)
+	}
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	for accept, expected := range map[string]string{
+		"":    "svg",
+		"*/*": "svg",
+		"image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8": "svg",
+		"image/avif,image/webp,*/*;q=0.8":                                  "avif",
+		"image/webp,*/*":                                                   "webp",
+		"image/png;q=0.9,image/webp;q=0.5":                                 "png",
+		"image/svg+xml;q=0,image/*":                                        "avif",
+		"text/html":                                                        "svg",
+	} {
+		if format := negotiateFormat(accept); format != expected {
+			t.Errorf("Expected %s for '%s' but got %s", expected, accept, format)
+		}
+	}
+}
+
+func TestHintedSize(t *testing.T) {
+	for _, test := range []struct {
+		header   http.Header
+		expected int
+	}{
+		{http.Header{}, 0},
+		{http.Header{"Dpr": {"2"}}, 2 * defaultRasterSize},
+		{http.Header{"Width": {"300"}, "Dpr": {"2"}}, 300},
+		{http.Header{"Sec-Ch-Width": {"5000"}}, maxConvertedSize},
+		{http.Header{"Dpr": {"0.01"}}, minConvertedSize},
+		{http.Header{"Dpr": {"abc"}}, 0},
+	} {
+		if size := hintedSize(test.header); size != test.expected {
+			t.Errorf("Expected %d for %v but got %d", test.expected, test.header, size)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/appditto/natricon/server/db"
 	"github.com/appditto/natricon/server/image"
@@ -12,19 +13,26 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// StatsMessage - a natricon that was served
+type StatsMessage struct {
+	Address  string
+	ClientIP string
+	Service  string // Value of the svc parameter, may be empty
+}
+
 // Go routine for processing stats messages
-func StatsWorker(statsChan <-chan *gin.Context) {
+func StatsWorker(statsChan <-chan *StatsMessage) {
 	// Process stats
-	for c := range statsChan {
+	for msg := range statsChan {
 		// Update unique addresses
-		db.GetDB().UpdateStatsAddress(c.Query("address"))
+		db.GetDB().UpdateStatsAddress(msg.Address)
 		// Update daily
-		db.GetDB().UpdateStatsDate(c.Query("address"))
+		db.GetDB().UpdateStatsDate(msg.Address)
 		// Update clients
-		db.GetDB().UpdateStatsClient(c.ClientIP())
+		db.GetDB().UpdateStatsClient(msg.ClientIP)
 		// Update by service
-		if c.Query("svc") != "" {
-			db.GetDB().UpdateStatsByService(c.Query("svc"), c.Query("address"))
+		if msg.Service != "" {
+			db.GetDB().UpdateStatsByService(msg.Service, msg.Address)
 		}
 	}
 }
@@ -134,3 +142,61 @@ func TestHairDistribution(seed string) {
 	print(fmt.Sprintf("S 60-80 %d\n", lt80))
 	print(fmt.Sprintf("S 80-100 %d\n", lt100))
 }
+
+// For comparing how often natricons of different addresses collide with every version of the algorithm
+// Version 1 seeds MT19937 with a few hex digits of the hash per trait, so traits can only take as many values as their seeds
+func TestCollisions(seed string, n int) {
+	// Hex digits of the hash each trait is seeded with by version 1, seeds are cut to 32 bits
+	v1SeedBits := map[string]int{"body_red": 16, "body_green": 16, "body_blue": 16, "body_color": 48, "hair_color": 64, "body": 24, "hair": 24, "mouth": 32, "eye": 32, "accessories": 64}
+	traits := []string{"body_red", "body_green", "body_blue", "body_color", "hair_color", "body", "hair", "mouth", "eye", "accessories", "visible", "natricon"}
+	addresses := make([]string, n)
+	for i := range addresses {
+		addresses[i] = utils.GenerateAddress()
+	}
+	print(fmt.Sprintf("%d random addresses, colliding pairs are pairs of addresses sharing the trait\n", n))
+	print(fmt.Sprintf("%-8s %-12s %-10s %-10s %-14s %s\n", "version", "trait", "v1 seed", "distinct", "colliding", "share"))
+	for _, v := range image.Versions {
+		counts := map[string]map[string]int{}
+		for _, trait := range traits {
+			counts[trait] = map[string]int{}
+		}
+		for _, address := range addresses {
+			sha256 := utils.AddressSha256(address, seed)
+			accessories, err := image.GetAccessoriesForHashVersion(sha256, v, spc.BTNone, false, nil)
+			if err != nil {
+				fmt.Printf("Failed to generate natricon for %s: %s\n", address, err)
+				return
+			}
+			keys := map[string]string{
+				"body_red":    fmt.Sprintf("%v", accessories.BodyColor.R),
+				"body_green":  fmt.Sprintf("%v", accessories.BodyColor.G),
+				"body_blue":   fmt.Sprintf("%v", accessories.BodyColor.B),
+				"body_color":  fmt.Sprintf("%v", accessories.BodyColor),
+				"hair_color":  fmt.Sprintf("%v", accessories.HairColor),
+				"body":        accessories.BodyAsset.FileName,
+				"hair":        accessories.HairAsset.FileName,
+				"mouth":       accessories.MouthAsset.FileName,
+				"eye":         accessories.EyeAsset.FileName,
+				"accessories": strings.Join(image.PickAccessories(sha256, v), ","),
+			}
+			// Identical in what people can tell apart, colors as they're written in the SVG
+			keys["visible"] = strings.Join([]string{accessories.BodyColor.ToHTML(true), accessories.HairColor.ToHTML(true), keys["body"], keys["hair"], keys["mouth"], keys["eye"], keys["accessories"]}, "|")
+			keys["natricon"] = strings.Join([]string{keys["body_color"], keys["hair_color"], keys["body"], keys["hair"], keys["mouth"], keys["eye"], keys["accessories"]}, "|")
+			for trait, key := range keys {
+				counts[trait][key]++
+			}
+		}
+		pairs := float64(n) * float64(n-1) / 2
+		for _, trait := range traits {
+			colliding := 0
+			for _, c := range counts[trait] {
+				colliding += c * (c - 1) / 2
+			}
+			bits := "-"
+			if b, ok := v1SeedBits[trait]; ok {
+				bits = fmt.Sprintf("%d bits", b)
+			}
+			print(fmt.Sprintf("%-8d %-12s %-10s %-10d %-14d %.6f\n", v, trait, bits, len(counts[trait]), colliding, float64(colliding)/pairs))
+		}
+	}
+}
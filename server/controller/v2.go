@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/appditto/natricon/server/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Name of the final path segment when a natricon has no size, or has the default size
+const v2DefaultName = "natricon"
+
+// Options that can be given as path segments in the v2 API
+// format and size come from the final segment instead
+var v2Options = []string{
+	"nonce",
+	"svc",
+	"quality",
+	"animate",
+	"outline",
+	"outline_color",
+	"outline_width",
+	"background",
+	"shape",
+	"parts",
+	"crop",
+	"css_vars",
+	"style",
+	"accessory",
+	"seasonal",
+	"v",
+}
+
+// parseV2Path - parse the path of a v2 request into an address and its query parameters
+// Paths are either /{address}.{ext} or /{address}/[{option}={value}/...]{size|natricon}.{ext}
+func parseV2Path(p string) (string, map[string]string, error) {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	last := segments[len(segments)-1]
+	ext := path.Ext(last)
+	if ext == "" || len(ext) == 1 {
+		return "", nil, errors.New("Path must end with a file extension")
+	}
+	query := map[string]string{"format": strings.ToLower(ext[1:])}
+	name := strings.TrimSuffix(last, ext)
+
+	if len(segments) == 1 {
+		return name, query, nil
+	}
+
+	if name != v2DefaultName {
+		size, err := strconv.Atoi(name)
+		if err != nil {
+			return "", nil, fmt.Errorf("File name must be a size or '%s'", v2DefaultName)
+		}
+		if query["format"] == "svg" || query["format"] == "ico" {
+			return "", nil, fmt.Errorf("%s natricons have no size, use '%s.%s'", query["format"], v2DefaultName, query["format"])
+		}
+		query["size"] = strconv.Itoa(size)
+	}
+
+	for _, segment := range segments[1 : len(segments)-1] {
+		kv := strings.SplitN(segment, "=", 2)
+		if len(kv) != 2 || !isV2Option(kv[0]) {
+			return "", nil, fmt.Errorf("Invalid option '%s', valid options are '%s'", segment, strings.Join(v2Options, "', '"))
+		}
+		if _, ok := query[kv[0]]; ok {
+			return "", nil, fmt.Errorf("Option '%s' given more than once", kv[0])
+		}
+		query[kv[0]] = kv[1]
+	}
+	return segments[0], query, nil
+}
+
+func isV2Option(key string) bool {
+	for _, option := range v2Options {
+		if option == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate natricon with the address, format, size and options in the path, query strings and headers are ignored
+// The natricon of a path still changes with seasonal overlays, which start and end at midnight UTC, and with the nonce stored for addresses without one in the path
+func (nc NatriconController) GetNanoV2(c *gin.Context) {
+	address, query, err := parseV2Path(c.Param("path"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+	valid := utils.ValidateAddress(address)
+	if !valid {
+		c.String(http.StatusBadRequest, "Invalid address")
+		return
+	}
+
+	// Parse stats
+	*nc.StatsChannel <- &StatsMessage{Address: address, ClientIP: c.ClientIP(), Service: query["svc"]}
+
+	nc.generateIcon(nc.resolveNatricon(address, query["nonce"]), c, func(key string) string {
+		return query[key]
+	})
+}
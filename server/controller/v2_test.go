@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseV2Path(t *testing.T) {
+	for _, test := range []struct {
+		path     string
+		address  string
+		expected map[string]string
+	}{
+		{"/nano_1abc.svg", "nano_1abc", map[string]string{"format": "svg"}},
+		{"/nano_1abc/256.PNG", "nano_1abc", map[string]string{"format": "png", "size": "256"}},
+		{"/nano_1abc/natricon.webp", "nano_1abc", map[string]string{"format": "webp"}},
+		{"/nano_1abc/outline=true/shape=circle/natricon.svg", "nano_1abc", map[string]string{"format": "svg", "outline": "true", "shape": "circle"}},
+		{"/nano_1abc/outline_color=#FF0000/64.jpg", "nano_1abc", map[string]string{"format": "jpg", "size": "64", "outline_color": "#FF0000"}},
+	} {
+		address, query, err := parseV2Path(test.path)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %s", test.path, err)
+			continue
+		}
+		if address != test.address || !reflect.DeepEqual(query, test.expected) {
+			t.Errorf("Expected %s %v for %s but got %s %v", test.address, test.expected, test.path, address, query)
+		}
+	}
+
+	for _, path := range []string{
+		"/nano_1abc",
+		"/nano_1abc/",
+		"/nano_1abc/256.svg",
+		"/nano_1abc/large.png",
+		"/nano_1abc/size=256/natricon.png",
+		"/nano_1abc/outline/natricon.svg",
+		"/nano_1abc/shape=circle/shape=square/natricon.svg",
+	} {
+		if _, _, err := parseV2Path(path); err == nil {
+			t.Errorf("Expected error for %s", path)
+		}
+	}
+}
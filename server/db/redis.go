@@ -226,7 +226,7 @@ func (r *redisManager) UpdateStatsAddress(address string) {
 			count = existingInt + 1
 		}
 	}
-	err = r.hset(key, address, string(count))
+	err = r.hset(key, address, strconv.Itoa(count))
 	if err != nil {
 		glog.Errorf("Error updating StatesAddresses %s", err)
 	}
@@ -256,7 +256,7 @@ func (r *redisManager) UpdateStatsDate(address string) {
 			count = existingInt + 1
 		}
 	}
-	err = r.hset(key, fmt.Sprintf("%s_%s", dateStr, address), string(count))
+	err = r.hset(key, fmt.Sprintf("%s_%s", dateStr, address), strconv.Itoa(count))
 	if err != nil {
 		glog.Errorf("Error updating StatsDate %s", err)
 	}
@@ -359,7 +359,7 @@ func (r *redisManager) UpdateStatsClient(ip string) {
 			count = existingInt + 1
 		}
 	}
-	err = r.hset(key, hashed, string(count))
+	err = r.hset(key, hashed, strconv.Itoa(count))
 	if err != nil {
 		glog.Errorf("Error updating StatsClient %s", err)
 	}
@@ -525,3 +525,13 @@ func (r *redisManager) SetNonce(pubkey string, nonce int) int {
 	r.hset(fmt.Sprintf("%s:nonces", keyPrefix), pubkey, strconv.Itoa(nonce))
 	return nonce
 }
+
+// GetRenderCache - get a cached rendered natricon
+func (r *redisManager) GetRenderCache(key string) ([]byte, error) {
+	return r.Client.Get(fmt.Sprintf("%s:render:%s", keyPrefix, key)).Bytes()
+}
+
+// SetRenderCache - cache a rendered natricon for the given duration
+func (r *redisManager) SetRenderCache(key string, data []byte, ttl time.Duration) error {
+	return r.Client.Set(fmt.Sprintf("%s:render:%s", keyPrefix, key), data, ttl).Err()
+}
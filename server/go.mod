@@ -1,29 +1,50 @@
 module github.com/appditto/natricon/server
 
-go 1.14
+go 1.23
 
 require (
+	github.com/HugoSmits86/nativewebp v0.9.3
 	github.com/ajstarks/svgo v0.0.0-20200320125537-f189e35d30ca
-	github.com/bbedward/crypto/ed25519 v0.0.0-20200408160247-f3ed4859f246 // indirect
 	github.com/bbedward/nano v0.0.0-20200408160834-45efd709c9fa
 	github.com/bsm/redislock v0.5.0
+	github.com/gen2brain/avif v0.4.4
 	github.com/gin-gonic/gin v1.6.3
-	github.com/go-playground/validator/v10 v10.3.0 // indirect
 	github.com/go-redis/redis/v7 v7.3.0
 	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b
-	github.com/golang/protobuf v1.4.2 // indirect
 	github.com/google/uuid v1.1.1
 	github.com/googollee/go-socket.io v1.4.3
-	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/jasonlvhit/gocron v0.0.0-20200423141508-ab84337f7963
+	github.com/recws-org/recws v1.2.1
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
+	github.com/tdewolff/minify/v2 v2.7.4
+	golang.org/x/image v0.0.0-20211028202545-6944b10bf410
+	gopkg.in/gographics/imagick.v3 v3.3.0
+)
+
+require (
+	github.com/bbedward/crypto/ed25519 v0.0.0-20200408160247-f3ed4859f246 // indirect
+	github.com/ebitengine/purego v0.8.3 // indirect
+	github.com/gin-contrib/sse v0.1.0 // indirect
+	github.com/go-playground/locales v0.13.0 // indirect
+	github.com/go-playground/universal-translator v0.17.0 // indirect
+	github.com/go-playground/validator/v10 v10.3.0 // indirect
+	github.com/golang/protobuf v1.4.2 // indirect
+	github.com/googollee/go-engine.io v1.4.3-0.20200220091802-9b2ab104b298 // indirect
+	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/jpillora/backoff v1.0.0 // indirect
+	github.com/json-iterator/go v1.1.9 // indirect
+	github.com/leodido/go-urn v1.2.0 // indirect
+	github.com/mattn/go-isatty v0.0.12 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.1 // indirect
-	github.com/recws-org/recws v1.2.1
-	github.com/tdewolff/minify/v2 v2.7.4
+	github.com/tdewolff/parse/v2 v2.4.2 // indirect
+	github.com/tetratelabs/wazero v1.9.0 // indirect
+	github.com/ugorji/go/codec v1.1.7 // indirect
 	golang.org/x/crypto v0.0.0-20200510223506-06a226fb4e37 // indirect
-	golang.org/x/sys v0.0.0-20200523222454-059865788121 // indirect
+	golang.org/x/net v0.0.0-20211118161319-6a13c67c3ce4 // indirect
+	golang.org/x/sys v0.0.0-20210423082822-04245dca01da // indirect
+	golang.org/x/text v0.3.6 // indirect
 	google.golang.org/protobuf v1.24.0 // indirect
-	gopkg.in/gographics/imagick.v3 v3.3.0
 	gopkg.in/yaml.v2 v2.3.0 // indirect
 )
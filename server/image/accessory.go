@@ -2,30 +2,58 @@ package image
 
 import (
 	"errors"
-	"fmt"
 	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/appditto/natricon/server/color"
-	"github.com/appditto/natricon/server/rand"
 	"github.com/appditto/natricon/server/spc"
 )
 
 // Accessories - represents accessories for natricon
 type Accessories struct {
-	BodyColor         color.RGB
-	HairColor         color.RGB
-	BodyAsset         Asset
-	HairAsset         Asset
-	MouthAsset        Asset
-	EyeAsset          Asset
-	BackHairAsset     *Asset
-	BodyOutlineAsset  *Asset
-	HairOutlineAsset  *Asset
-	MouthOutlineAsset *Asset
-	BadgeAsset        *Asset
-	OutlineColor      color.RGB
+	Version              Version // Version of the generation algorithm the natricon was generated with, V1 when 0
+	BodyColor            color.RGB
+	HairColor            color.RGB
+	BodyAsset            Asset
+	HairAsset            Asset
+	MouthAsset           Asset
+	EyeAsset             Asset
+	BackHairAsset        *Asset
+	BodyOutlineAsset     *Asset
+	HairOutlineAsset     *Asset
+	MouthOutlineAsset    *Asset
+	BadgeAsset           *Asset
+	HatAsset             *Asset // Optional accessory drawn over the hair
+	HatOutlineAsset      *Asset
+	GlassesAsset         *Asset   // Optional accessory drawn over the eyes
+	OverlayAssets        []*Asset // Seasonal overlays drawn over the hair
+	OverlayOutlineAssets []*Asset
+	OutlineColor         color.RGB
+	OutlineWidth         float64    // Stroke width of outlines, DefaultOutlineWidth when 0
+	Background           *color.RGB // Fill behind the natricon, transparent when nil
+	Shape                Shape      // Shape the natricon and its background are clipped to, square when empty
+	Parts                []Part     // Parts that are rendered, every part when nil
+	Crop                 Crop       // Part of the natricon the view box is framed around, full when empty
+	CSSVars              bool       // Write colors as CSS variables so natricons can be re-themed by the page embedding them
+}
+
+// version - version of the generation algorithm the natricon was generated with
+func (a Accessories) version() Version {
+	if a.Version > 0 {
+		return a.Version
+	}
+	return V1
+}
+
+// Sex - sex of the natricon, decided by the first of body, hair and mouth that isn't neutral
+func (a Accessories) Sex() Sex {
+	for _, asset := range []Asset{a.BodyAsset, a.HairAsset, a.MouthAsset} {
+		// Assets that haven't been picked yet have no sex
+		if asset.Sex != Neutral && asset.Sex != "" {
+			return asset.Sex
+		}
+	}
+	return Neutral
 }
 
 // Hex string regex
@@ -46,9 +74,9 @@ func GetSpecificNatricon(badgeType spc.BadgeType, outline bool, outlineColor *co
 	accessories.HairAsset = GetHairAssetWithID(hairAsset)
 	accessories.BackHairAsset = GetBackHairAsset(accessories.HairAsset)
 
-	// Get badge
+	// Get badge, placed like version 1 so vanity natricons don't change
 	if badgeType != "" && badgeType != spc.BTNone {
-		accessories.BadgeAsset = GetBadgeAsset(accessories.BodyAsset, badgeType)
+		accessories.BadgeAsset = GetBadgeAsset(accessories.BodyAsset, badgeType, V1)
 	}
 
 	// Eyes and mouth
@@ -70,50 +98,50 @@ func GetSpecificNatricon(badgeType spc.BadgeType, outline bool, outlineColor *co
 	return accessories
 }
 
-// GetAccessoriesForHash - Return Accessories object based on 64-character hex string
+// GetAccessoriesForHash - Return Accessories object based on 64-character hex string, generated with DefaultVersion
 func GetAccessoriesForHash(hash string, badgeType spc.BadgeType, outline bool, outlineColor *color.RGB) (Accessories, error) {
-	var err error
+	return GetAccessoriesForHashVersion(hash, DefaultVersion, badgeType, outline, outlineColor)
+}
+
+// GetAccessoriesForHashVersion - Return Accessories object based on 64-character hex string, generated with a version of the algorithm
+func GetAccessoriesForHashVersion(hash string, v Version, badgeType spc.BadgeType, outline bool, outlineColor *color.RGB) (Accessories, error) {
 	if len(hash) != 64 {
 		return Accessories{}, errors.New("Invalid hash")
 	}
+	if _, ok := algorithms[v]; !ok {
+		return Accessories{}, errors.New("Invalid version")
+	}
 	// Validate is a hex string
 	if !hexRegex.MatchString(hash) {
 		return Accessories{}, errors.New("Invalid hash")
 	}
 
-	// Create empty Accessories object
-	var accessories = Accessories{}
-	// Body color uses first 12 digits of hash as seed
-	accessories.BodyColor, err = GetBodyColor(hash[0:16])
+	// Random sources of every trait, derived from the hash as the version says
+	src, err := v.traitSources(hash)
 	if err != nil {
 		return Accessories{}, err
 	}
 
+	// Create empty Accessories object
+	var accessories = Accessories{Version: v}
+	accessories.BodyColor = GetBodyColor(src.bodyRed, src.bodyGreen, src.bodyBlue)
+
 	// Get hair color
-	accessories.HairColor, err = GetHairColor(accessories.BodyColor, hash[16:26], hash[26:30], hash[30:34])
+	accessories.HairColor = GetHairColor(accessories.BodyColor, src.hairHue, src.hairSaturation, src.hairBrightness)
 
 	// Get body and hair illustrations
-	accessories.BodyAsset, err = GetBodyAsset(hash[34:40])
-	accessories.HairAsset, err = GetHairAsset(hash[40:46], &accessories.BodyAsset)
+	accessories.BodyAsset = GetBodyAsset(src.body, v)
+	accessories.HairAsset = GetHairAsset(src.hair, &accessories.BodyAsset, v)
 	accessories.BackHairAsset = GetBackHairAsset(accessories.HairAsset)
 
 	// Get badge
 	if badgeType != "" && badgeType != spc.BTNone {
-		accessories.BadgeAsset = GetBadgeAsset(accessories.BodyAsset, badgeType)
+		accessories.BadgeAsset = GetBadgeAsset(accessories.BodyAsset, badgeType, v)
 	}
 
 	// Get mouth and eyes
-	targetSex := Neutral
-	if accessories.BodyAsset.Sex != Neutral {
-		targetSex = accessories.BodyAsset.Sex
-	} else if accessories.HairAsset.Sex != Neutral {
-		targetSex = accessories.HairAsset.Sex
-	}
-	accessories.MouthAsset, err = GetMouthAsset(hash[46:55], targetSex, accessories.BodyColor.PerceivedBrightness())
-	if targetSex == Neutral && accessories.MouthAsset.Sex != Neutral {
-		targetSex = accessories.MouthAsset.Sex
-	}
-	accessories.EyeAsset, err = GetEyeAsset(hash[55:64], targetSex, accessories.BodyColor.PerceivedBrightness())
+	accessories.MouthAsset = GetMouthAsset(src.mouth, accessories.Sex(), accessories.BodyColor.PerceivedBrightness(), v)
+	accessories.EyeAsset = GetEyeAsset(src.eye, accessories.Sex(), accessories.BodyColor.PerceivedBrightness(), v)
 
 	// Get outlines
 	if outline {
@@ -130,32 +158,34 @@ func GetAccessoriesForHash(hash string, badgeType spc.BadgeType, outline bool, o
 	return accessories, nil
 }
 
-// GetBodyAsset - return body illustration to use with given entropy
-func GetBodyAsset(entropy string) (Asset, error) {
-	// Get detemrinistic RNG
-	randSeed, err := strconv.ParseInt(entropy, 16, 64)
-	if err != nil {
-		return Asset{}, err
+// pickWeighted - pick index of an asset with the RNG, favoring assets with a higher weight
+// extra outcomes that aren't assets can be added with a weight of 1 each, picking them returns len(assets)
+// With every weight at 1 this is the same as picking an index uniformly
+func pickWeighted(r randSource, assets []Asset, extra int) int {
+	total := extra
+	for _, a := range assets {
+		total += a.weight()
 	}
+	pick := int(r.Int31n(int32(total)))
+	for i, a := range assets {
+		if pick < a.weight() {
+			return i
+		}
+		pick -= a.weight()
+	}
+	return len(assets)
+}
 
-	r := rand.Init()
-	r.Seed(uint32(randSeed))
-	bodyIndex := r.Int31n(int32(GetAssets().GetNBodyAssets()))
-
-	return GetAssets().GetBodyAssets()[bodyIndex], nil
+// GetBodyAsset - return body illustration to pick with a random source
+func GetBodyAsset(r randSource, v Version) Asset {
+	bodyAssetOptions := v.snapshot(GetAssets().GetBodyAssets())
+	return bodyAssetOptions[v.pick(r, bodyAssetOptions, 0)]
 }
 
 // GetBodyAssetWithID - return body illustration with given ID
 func GetBodyAssetWithID(id int) Asset {
 	for _, ba := range GetAssets().GetBodyAssets() {
-		baid, err := strconv.Atoi(strings.Split(ba.FileName, "_")[0])
-		if err != nil {
-			baid, err = strconv.Atoi(strings.Split(ba.FileName, ".")[0])
-			if err != nil {
-				continue
-			}
-		}
-		if baid == id {
+		if ba.ID() == id {
 			return ba
 		}
 	}
@@ -173,45 +203,35 @@ func GetBodyOutlineAsset(bodyAsset Asset) *Asset {
 }
 
 // GetBadgeAsset - return badge asset for a particular body
-func GetBadgeAsset(bodyAsset Asset, btype spc.BadgeType) *Asset {
-	identifier, _ := strconv.Atoi(strings.Split(bodyAsset.FileName, "_")[0])
-	searchStr := fmt.Sprintf("b%d", identifier)
-	for _, v := range GetAssets().GetBadgeAssets(btype) {
-		if strings.Contains(v.FileName, searchStr) {
-			return &v
+// Version 1 takes the first badge with b<body> anywhere in its file name, so bodies 1 and 2 get the badge of bodies 11 to 21
+func GetBadgeAsset(bodyAsset Asset, btype spc.BadgeType, v Version) *Asset {
+	searchStr := "b" + strings.Split(bodyAsset.FileName, "_")[0]
+	for _, b := range GetAssets().GetBadgeAssets(btype) {
+		if !algorithms[v].badges {
+			if strings.Contains(b.FileName, searchStr) {
+				return &b
+			}
+			continue
+		}
+		for _, id := range b.BadgeBodies {
+			if id == bodyAsset.ID() {
+				return &b
+			}
 		}
 	}
 	return nil
 }
 
-// GetHairAsset - return hair illustration to use with given entropy
-func GetHairAsset(entropy string, bodyAsset *Asset) (Asset, error) {
-	// Get detemrinistic RNG
-	randSeed, err := strconv.ParseInt(entropy, 16, 64)
-	if err != nil {
-		return Asset{}, err
-	}
-
-	hairAssetOptions := GetAssets().GetHairAssets(bodyAsset.Sex)
-
-	r := rand.Init()
-	r.Seed(uint32(randSeed))
-	hairIndex := r.Int31n(int32(len(hairAssetOptions)))
-
-	return hairAssetOptions[hairIndex], nil
+// GetHairAsset - return hair illustration to pick with a random source
+func GetHairAsset(r randSource, bodyAsset *Asset, v Version) Asset {
+	hairAssetOptions := v.snapshot(GetAssets().GetHairAssets(bodyAsset.Sex))
+	return hairAssetOptions[v.pick(r, hairAssetOptions, 0)]
 }
 
 // GetHairAssetWithID - return body illustration with given ID
 func GetHairAssetWithID(id int) Asset {
 	for _, ha := range GetAssets().GetHairAssets(Neutral) {
-		haid, err := strconv.Atoi(strings.Split(ha.FileName, "_")[0])
-		if err != nil {
-			haid, err = strconv.Atoi(strings.Split(ha.FileName, ".")[0])
-			if err != nil {
-				continue
-			}
-		}
-		if haid == id {
+		if ha.ID() == id {
 			return ha
 		}
 	}
@@ -238,68 +258,32 @@ func GetHairOutlineAsset(hairAsset Asset) *Asset {
 	return nil
 }
 
-// GetEyeAsset - return hair illustration to use with given entropy
-func GetEyeAsset(entropy string, sex Sex, luminosity float64) (Asset, error) {
-	// Get detemrinistic RNG
-	randSeed, err := strconv.ParseInt(entropy, 16, 64)
-	if err != nil {
-		return Asset{}, err
-	}
-
-	eyeAssetOptions := GetAssets().GetEyeAssets(sex, luminosity)
-
-	r := rand.Init()
-	r.Seed(uint32(randSeed))
-	eyeIndex := r.Int31n(int32(len(eyeAssetOptions)))
-
-	return eyeAssetOptions[eyeIndex], nil
+// GetEyeAsset - return eye illustration to pick with a random source
+func GetEyeAsset(r randSource, sex Sex, luminosity float64, v Version) Asset {
+	eyeAssetOptions := v.snapshot(GetAssets().GetEyeAssets(sex, luminosity))
+	return eyeAssetOptions[v.pick(r, eyeAssetOptions, 0)]
 }
 
 // GetEyeAssetWithID - return eye illustration with given ID
 func GetEyeAssetWithID(id int) Asset {
 	for _, ba := range GetAssets().GetEyeAssets(Neutral, 100) {
-		baid, err := strconv.Atoi(strings.Split(ba.FileName, "_")[0])
-		if err != nil {
-			baid, err = strconv.Atoi(strings.Split(ba.FileName, ".")[0])
-			if err != nil {
-				continue
-			}
-		}
-		if baid == id {
+		if ba.ID() == id {
 			return ba
 		}
 	}
 	return GetAssets().GetEyeAssets(Neutral, 100)[0]
 }
 
-// GetEyeAsset - return hair illustration to use with given entropy
-func GetMouthAsset(entropy string, sex Sex, luminosity float64) (Asset, error) {
-	// Get detemrinistic RNG
-	randSeed, err := strconv.ParseInt(entropy, 16, 64)
-	if err != nil {
-		return Asset{}, err
-	}
-
-	mouthAssetOptions := GetAssets().GetMouthAssets(sex, luminosity)
-
-	r := rand.Init()
-	r.Seed(uint32(randSeed))
-	mouthIndex := r.Int31n(int32(len(mouthAssetOptions)))
-
-	return mouthAssetOptions[mouthIndex], nil
+// GetMouthAsset - return mouth illustration to pick with a random source
+func GetMouthAsset(r randSource, sex Sex, luminosity float64, v Version) Asset {
+	mouthAssetOptions := v.snapshot(GetAssets().GetMouthAssets(sex, luminosity))
+	return mouthAssetOptions[v.pick(r, mouthAssetOptions, 0)]
 }
 
 // GetMouthAssetWithID - return mouth illustration with given ID
 func GetMouthAssetWithID(id int) Asset {
 	for _, ba := range GetAssets().GetMouthAssets(Neutral, 100) {
-		baid, err := strconv.Atoi(strings.Split(ba.FileName, "_")[0])
-		if err != nil {
-			baid, err = strconv.Atoi(strings.Split(ba.FileName, ".")[0])
-			if err != nil {
-				continue
-			}
-		}
-		if baid == id {
+		if ba.ID() == id {
 			return ba
 		}
 	}
@@ -0,0 +1,146 @@
+package image
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AccessoryAuto - pick accessories from the hash of the natricon
+const AccessoryAuto = "auto"
+
+// AccessoryNone - draw no accessories
+const AccessoryNone = "none"
+
+// accessoryTypes - illustration types of optional accessory layers, at most one of each is worn
+var accessoryTypes = []IllustrationType{Hat, Glasses}
+
+// accessoryAssets - assets of an accessory layer type
+func (sm *assetManager) accessoryAssets(iType IllustrationType) []Asset {
+	switch iType {
+	case Hat:
+		return sm.hatAssets
+	case Glasses:
+		return sm.glassesAssets
+	}
+	return nil
+}
+
+// AccessoryName - name of an accessory asset, its file name without ID and extension
+func (a Asset) AccessoryName() string {
+	name := strings.TrimSuffix(a.FileName, ".svg")
+	if i := strings.Index(name, "_"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// AccessoryNames - names of every accessory, in layer order
+func AccessoryNames() []string {
+	var names []string
+	for _, iType := range accessoryTypes {
+		for _, a := range GetAssets().accessoryAssets(iType) {
+			names = append(names, a.AccessoryName())
+		}
+	}
+	return names
+}
+
+// findAccessory - get accessory asset and its type by name
+func findAccessory(name string) (*Asset, IllustrationType, bool) {
+	for _, iType := range accessoryTypes {
+		assets := GetAssets().accessoryAssets(iType)
+		for i := range assets {
+			if assets[i].AccessoryName() == name {
+				return &assets[i], iType, true
+			}
+		}
+	}
+	return nil, "", false
+}
+
+// ParseAccessories - parse comma separated list of accessory names, in layer order
+// Returns nil for 'none' and an empty list
+func ParseAccessories(list string) ([]string, error) {
+	var names []string
+	worn := map[IllustrationType]string{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || name == AccessoryNone {
+			continue
+		}
+		_, iType, ok := findAccessory(name)
+		if !ok {
+			return nil, fmt.Errorf("Unknown accessory %s", name)
+		} else if other, ok := worn[iType]; ok && other != name {
+			return nil, fmt.Errorf("Accessories %s and %s can't be worn together", other, name)
+		}
+		if _, ok := worn[iType]; !ok {
+			worn[iType] = name
+			names = append(names, name)
+		}
+	}
+	order := AccessoryNames()
+	sort.Slice(names, func(i, j int) bool { return indexOf(order, names[i]) < indexOf(order, names[j]) })
+	return names, nil
+}
+
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// PickAccessories - deterministically pick accessories for a natricon
+// Every layer type can also pick nothing
+func PickAccessories(hash string, v Version) []string {
+	var names []string
+	for i, iType := range accessoryTypes {
+		assets := v.snapshot(GetAssets().accessoryAssets(iType))
+		if len(assets) == 0 {
+			continue
+		}
+		r, err := v.accessorySource(hash, i, iType)
+		if err != nil {
+			return nil
+		}
+		index := v.pick(r, assets, 1)
+		if index < len(assets) {
+			names = append(names, assets[index].AccessoryName())
+		}
+	}
+	return names
+}
+
+// Wear - put accessories on the natricon by name, with outlines when the natricon is outlined
+func (a *Accessories) Wear(names []string) error {
+	for _, name := range names {
+		asset, iType, ok := findAccessory(name)
+		if !ok {
+			return fmt.Errorf("Unknown accessory %s", name)
+		}
+		switch iType {
+		case Hat:
+			a.HatAsset = asset
+			if a.BodyOutlineAsset != nil {
+				a.HatOutlineAsset = GetHatOutlineAsset(*asset)
+			}
+		case Glasses:
+			a.GlassesAsset = asset
+		}
+	}
+	return nil
+}
+
+// GetHatOutlineAsset - return hat outline illustration for a given hat asset
+func GetHatOutlineAsset(hatAsset Asset) *Asset {
+	for _, ha := range GetAssets().hatOutlineAssets {
+		if ha.FileName == hatAsset.FileName {
+			return &ha
+		}
+	}
+	return nil
+}
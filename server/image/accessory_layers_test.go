@@ -0,0 +1,56 @@
+package image
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/appditto/natricon/server/color"
+	"github.com/appditto/natricon/server/spc"
+)
+
+func TestParseAccessories(t *testing.T) {
+	if names, err := ParseAccessories("round-glasses, party-hat"); err != nil || !reflect.DeepEqual(names, []string{"party-hat", "round-glasses"}) {
+		t.Errorf("Expected accessories in layer order but got %v %v", names, err)
+	}
+	if names, err := ParseAccessories(AccessoryNone); err != nil || names != nil {
+		t.Errorf("Expected no accessories but got %v %v", names, err)
+	}
+	if _, err := ParseAccessories("crown"); err == nil {
+		t.Error("Expected error for unknown accessory")
+	}
+}
+
+func TestPickAccessories(t *testing.T) {
+	if !reflect.DeepEqual(PickAccessories(benchHash, V1), PickAccessories(benchHash, V1)) {
+		t.Error("Expected the same accessories for the same hash")
+	}
+	picked := map[string]bool{}
+	for _, hash := range []string{benchHash, "a", "b", "c", "d", "e", "f", "g"} {
+		for _, name := range PickAccessories(hash, V1) {
+			picked[name] = true
+		}
+	}
+	if len(picked) == 0 {
+		t.Error("Expected some natricons to wear accessories")
+	}
+}
+
+func TestWear(t *testing.T) {
+	accessories, _ := GetAccessoriesForHash(benchHash, spc.BTNone, true, &color.RGB{R: 0, G: 0, B: 0})
+	if err := accessories.Wear([]string{"party-hat", "round-glasses"}); err != nil {
+		t.Fatalf("Failed to wear accessories %s", err)
+	}
+	if accessories.HatAsset == nil || accessories.HatOutlineAsset == nil || accessories.GlassesAsset == nil {
+		t.Fatal("Expected hat with outline and glasses")
+	}
+	svg, _ := CombineSVG(accessories)
+	for _, id := range []string{"hatOutline", "hat", "glasses"} {
+		if !bytes.Contains(svg, []byte("id=\""+id+"\"")) {
+			t.Errorf("Expected %s layer", id)
+		}
+	}
+	if err := accessories.Wear([]string{"crown"}); err == nil {
+		t.Error("Expected error for unknown accessory")
+	}
+}
@@ -0,0 +1,306 @@
+package image
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/appditto/natricon/server/rand"
+)
+
+// Animation - name of an animation a natricon can perform
+type Animation string
+
+const (
+	Blink     Animation = "blink"     // Eyes close briefly
+	Typing    Animation = "typing"    // Mouth alternates as if the natricon is talking
+	Bob       Animation = "bob"       // Hair bobs up and down
+	Celebrate Animation = "celebrate" // Natricon jumps and squints, hair follows with a delay
+)
+
+// Animations - all supported animations
+var Animations = []Animation{Blink, Typing, Bob, Celebrate}
+
+// MaxPoses - most distinct poses a frame of any animation can be in, each is rasterized once
+const MaxPoses = 8
+
+const eyeCenterY = 258.0      // Vertical center of eye assets, eyes are scaled around it when closing
+const closedEyeScale = 0.1    // Vertical scale of eyes that are fully closed
+const smilNumberPrecision = 3 // Decimals of numbers in transforms and SMIL values
+
+// Pose - how a natricon deviates from its still image in a single frame
+// The zero value is the still image
+type Pose struct {
+	OffsetY           float64 // Vertical offset of the whole natricon
+	HairOffsetY       float64 // Vertical offset of hair, in addition to OffsetY
+	EyeClosed         float64 // 0 when eyes are open, 1 when they are closed
+	MouthAsset        *Asset  // Mouth shown instead of the natricon's mouth, nil for its own mouth
+	MouthOutlineAsset *Asset  // Outline of MouthAsset when the natricon is outlined
+}
+
+// Frame - a pose and the time it is shown for
+type Frame struct {
+	Pose
+	Delay int // Milliseconds
+}
+
+// ParseAnimation - get animation with the given name
+func ParseAnimation(name string) (Animation, bool) {
+	for _, a := range Animations {
+		if string(a) == strings.ToLower(name) {
+			return a, true
+		}
+	}
+	return "", false
+}
+
+// animationRNG - deterministic RNG for animations, seeded with the colors of the natricon
+func animationRNG(accessories Accessories) *rand.MT19937 {
+	body, _ := strconv.ParseUint(accessories.BodyColor.ToHTML(false), 16, 32)
+	hair, _ := strconv.ParseUint(accessories.HairColor.ToHTML(false), 16, 32)
+	r := rand.Init()
+	r.Seed(uint32(body) ^ uint32(hair)<<8)
+	return r
+}
+
+// getAlternateMouth - pick a mouth other than the natricon's own, for talking, from the mouths of its version
+func getAlternateMouth(accessories Accessories, r *rand.MT19937) (*Asset, *Asset) {
+	var options []Asset
+	for _, m := range accessories.version().snapshot(GetAssets().GetMouthAssets(accessories.Sex(), accessories.BodyColor.PerceivedBrightness())) {
+		if m.FileName != accessories.MouthAsset.FileName {
+			options = append(options, m)
+		}
+	}
+	if len(options) == 0 {
+		return nil, nil
+	}
+	mouth := options[r.Int31n(int32(len(options)))]
+	var outline *Asset
+	if accessories.MouthOutlineAsset != nil {
+		outline = GetMouthOutlineAsset(mouth)
+	}
+	return &mouth, outline
+}
+
+// GetAnimationFrames - get frames of an animation, the same natricon always gets the same frames
+func GetAnimationFrames(accessories Accessories, animation Animation) []Frame {
+	r := animationRNG(accessories)
+	var frames []Frame
+	switch animation {
+	case Blink:
+		frames = append(frames,
+			Frame{Delay: 1800 + int(r.Int31n(1600))},
+			Frame{Pose: Pose{EyeClosed: 0.5}, Delay: 60},
+			Frame{Pose: Pose{EyeClosed: 1}, Delay: 100},
+			Frame{Pose: Pose{EyeClosed: 0.5}, Delay: 60},
+		)
+		// Some natricons blink twice
+		if r.Int31n(3) == 0 {
+			frames = append(frames,
+				Frame{Delay: 160},
+				Frame{Pose: Pose{EyeClosed: 1}, Delay: 100},
+				Frame{Pose: Pose{EyeClosed: 0.5}, Delay: 60},
+			)
+		}
+	case Typing:
+		mouth, outline := getAlternateMouth(accessories, r)
+		talking := Pose{MouthAsset: mouth, MouthOutlineAsset: outline}
+		// Start with the natricon's own mouth, it's the still image of some formats
+		frames = append(frames, Frame{Delay: 600})
+		words := 2 + int(r.Int31n(3))
+		for i := 0; i < words; i++ {
+			frames = append(frames, Frame{Pose: talking, Delay: 140 + int(r.Int31n(80))}, Frame{Delay: 140})
+		}
+	case Bob:
+		amplitude := 6 + float64(r.Int31n(5))
+		for _, f := range []float64{0, -0.4, -0.8, -1, -0.8, -0.4, 0, 0.3} {
+			frames = append(frames, Frame{Pose: Pose{HairOffsetY: f * amplitude}, Delay: 90})
+		}
+	case Celebrate:
+		height := 24 + float64(r.Int31n(9))
+		jump := []float64{0, 0.45, 0.8, 1, 0.8, 0.45, 0}
+		for i, f := range jump {
+			pose := Pose{OffsetY: -f * height}
+			// Hair lags one frame behind the body
+			if i > 0 {
+				pose.HairOffsetY = (f - jump[i-1]) * height
+			}
+			if f >= 0.8 {
+				pose.EyeClosed = 0.6
+			}
+			frames = append(frames, Frame{Pose: pose, Delay: 70})
+		}
+		frames = append(frames, Frame{Delay: 500})
+	default:
+		frames = append(frames, Frame{Delay: 1000})
+	}
+	return frames
+}
+
+// translate - transform moving a group vertically, empty for no movement
+func translate(y float64) string {
+	if y == 0 {
+		return ""
+	}
+	return fmt.Sprintf("translate(0 %s)", formatNumber(y))
+}
+
+// eyeScale - vertical scale of eyes closed by the given amount
+func eyeScale(closed float64) float64 {
+	return 1 - closed*(1-closedEyeScale)
+}
+
+// formatNumber - format number for SVG attributes, rounded to smilNumberPrecision decimals
+func formatNumber(f float64) string {
+	scale := math.Pow10(smilNumberPrecision)
+	// Adding zero turns negative zero into zero
+	return strconv.FormatFloat(math.Round(f*scale)/scale+0, 'f', -1, 64)
+}
+
+// isHairLayer - whether layer moves with hair
+func isHairLayer(id string) bool {
+	return id == "hair" || id == "backhair" || id == "hairOutline" || id == "hat" || id == "hatOutline" || strings.HasPrefix(id, "overlay")
+}
+
+// poseAccessories - accessories with the mouth of a pose
+func poseAccessories(accessories Accessories, pose Pose) Accessories {
+	if pose.MouthAsset != nil {
+		accessories.MouthAsset = *pose.MouthAsset
+		if accessories.MouthOutlineAsset != nil {
+			accessories.MouthOutlineAsset = pose.MouthOutlineAsset
+		}
+	}
+	return accessories
+}
+
+// CombineFrameSVG - create SVG of a natricon in the given pose
+func CombineFrameSVG(accessories Accessories, pose Pose) ([]byte, error) {
+	layers := buildLayers(poseAccessories(accessories, pose))
+	for i := range layers {
+		offset := pose.OffsetY
+		if isHairLayer(layers[i].id) {
+			offset += pose.HairOffsetY
+		}
+		transform := translate(offset)
+		if layers[i].id == "eye" && pose.EyeClosed != 0 {
+			scale := eyeScale(pose.EyeClosed)
+			transform = strings.TrimSpace(fmt.Sprintf("%s translate(0 %s) scale(1 %s)", transform, formatNumber(eyeCenterY*(1-scale)), formatNumber(scale)))
+		}
+		if transform != "" {
+			layers[i].attrs = append(layers[i].attrs, fmt.Sprintf("transform=\"%s\"", transform))
+		}
+	}
+	return writeSVG(accessories, layers)
+}
+
+// smilTimeline - timing shared by every SMIL animation of a natricon
+type smilTimeline struct {
+	keyTimes string
+	dur      string
+}
+
+func newSmilTimeline(frames []Frame) smilTimeline {
+	total := 0
+	for _, f := range frames {
+		total += f.Delay
+	}
+	keyTimes := make([]string, len(frames))
+	elapsed := 0
+	for i, f := range frames {
+		keyTimes[i] = formatNumber(float64(elapsed) / float64(total))
+		elapsed += f.Delay
+	}
+	return smilTimeline{
+		keyTimes: strings.Join(keyTimes, ";"),
+		dur:      fmt.Sprintf("%dms", total),
+	}
+}
+
+// animate - SMIL element animating an attribute through values, empty if the value never changes
+func (t smilTimeline) animate(element string, attrs string, values []string) string {
+	changes := false
+	for _, v := range values {
+		changes = changes || v != values[0]
+	}
+	if !changes {
+		return ""
+	}
+	return fmt.Sprintf("<%s %s values=\"%s\" keyTimes=\"%s\" dur=\"%s\" calcMode=\"discrete\" repeatCount=\"indefinite\"/>", element, attrs, strings.Join(values, ";"), t.keyTimes, t.dur)
+}
+
+// translateValues - SMIL translate values from vertical offsets
+func translateValues(offsets []float64) []string {
+	values := make([]string, len(offsets))
+	for i, y := range offsets {
+		values[i] = "0 " + formatNumber(y)
+	}
+	return values
+}
+
+// CombineAnimatedSVG - create SVG of a natricon animated with SMIL
+func CombineAnimatedSVG(accessories Accessories, frames []Frame) ([]byte, error) {
+	if len(frames) == 0 {
+		return CombineSVG(accessories)
+	}
+	timeline := newSmilTimeline(frames)
+	// Talking natricons have a second mouth that is only visible in some frames
+	var altMouth Pose
+	for _, f := range frames {
+		if f.MouthAsset != nil {
+			altMouth = f.Pose
+			break
+		}
+	}
+	var altLayers = map[string]layer{}
+	if altMouth.MouthAsset != nil {
+		for _, l := range buildLayers(poseAccessories(accessories, altMouth)) {
+			if l.id == "mouth" || l.id == "mouthOutline" {
+				altLayers[l.id] = l
+			}
+		}
+	}
+
+	var layers []layer
+	for _, l := range buildLayers(accessories) {
+		offsets := make([]float64, len(frames))
+		for i, f := range frames {
+			offsets[i] = f.OffsetY
+			if isHairLayer(l.id) {
+				offsets[i] += f.HairOffsetY
+			}
+		}
+		l.smil = timeline.animate("animateTransform", "attributeName=\"transform\" type=\"translate\" additive=\"sum\"", translateValues(offsets))
+		if l.id == "eye" {
+			// Scale eyes around their center
+			scaleOffsets := make([]float64, len(frames))
+			scales := make([]string, len(frames))
+			for i, f := range frames {
+				scale := eyeScale(f.EyeClosed)
+				scaleOffsets[i] = eyeCenterY * (1 - scale)
+				scales[i] = "1 " + formatNumber(scale)
+			}
+			l.smil += timeline.animate("animateTransform", "attributeName=\"transform\" type=\"translate\" additive=\"sum\"", translateValues(scaleOffsets))
+			l.smil += timeline.animate("animateTransform", "attributeName=\"transform\" type=\"scale\" additive=\"sum\"", scales)
+		}
+		alt, hasAlt := altLayers[l.id]
+		if !hasAlt {
+			layers = append(layers, l)
+			continue
+		}
+		visible := make([]string, len(frames))
+		altVisible := make([]string, len(frames))
+		for i, f := range frames {
+			visible[i], altVisible[i] = "visible", "hidden"
+			if f.MouthAsset != nil {
+				visible[i], altVisible[i] = "hidden", "visible"
+			}
+		}
+		alt.id = l.id + "Alt"
+		alt.attrs = append(alt.attrs, "visibility=\"hidden\"")
+		alt.smil = l.smil + timeline.animate("animate", "attributeName=\"visibility\"", altVisible)
+		l.smil += timeline.animate("animate", "attributeName=\"visibility\"", visible)
+		layers = append(layers, l, alt)
+	}
+	return writeSVG(accessories, layers)
+}
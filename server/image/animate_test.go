@@ -0,0 +1,120 @@
+package image
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/appditto/natricon/server/spc"
+)
+
+func TestParseAnimation(t *testing.T) {
+	if a, ok := ParseAnimation("Blink"); !ok || a != Blink {
+		t.Errorf("Expected blink but got %s", a)
+	}
+	if _, ok := ParseAnimation("dance"); ok {
+		t.Error("Expected dance to be invalid")
+	}
+}
+
+func TestAnimationFramesDeterministic(t *testing.T) {
+	accessories, _ := GetAccessoriesForHash(benchHash, spc.BTNone, true, nil)
+	for _, a := range Animations {
+		frames := GetAnimationFrames(accessories, a)
+		if len(frames) < 2 {
+			t.Errorf("Expected multiple frames for %s but got %d", a, len(frames))
+		}
+		if !reflect.DeepEqual(frames, GetAnimationFrames(accessories, a)) {
+			t.Errorf("Frames of %s are not deterministic", a)
+		}
+	}
+}
+
+func TestAnimationPosesBounded(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		accessories, _ := GetAccessoriesForHash(sampleHash(i), spc.BTNone, false, nil)
+		for _, a := range Animations {
+			poses := map[Pose]bool{}
+			for _, frame := range GetAnimationFrames(accessories, a) {
+				poses[frame.Pose] = true
+			}
+			if len(poses) > MaxPoses {
+				t.Fatalf("Expected at most %d poses but %s has %d", MaxPoses, a, len(poses))
+			}
+		}
+	}
+}
+
+func TestAlternateMouthFromVersion(t *testing.T) {
+	var natricons []Accessories
+	for i := 0; i < 100; i++ {
+		for _, v := range Versions {
+			accessories, _ := GetAccessoriesForHashVersion(sampleHash(i), v, spc.BTNone, false, nil)
+			natricons = append(natricons, accessories)
+		}
+	}
+	// Every other mouth is added in version 2
+	sm := GetAssets()
+	mouths := sm.mouthAssets
+	defer func() { sm.mouthAssets = mouths }()
+	sm.mouthAssets = append([]Asset{}, mouths...)
+	for i := 1; i < len(sm.mouthAssets); i += 2 {
+		sm.mouthAssets[i].Since = V2
+	}
+
+	newer := 0
+	for _, accessories := range natricons {
+		mouth, _ := getAlternateMouth(accessories, animationRNG(accessories))
+		if mouth == nil {
+			continue
+		} else if mouth.since() > accessories.Version {
+			t.Errorf("Expected mouths of version %d natricons from version %d got %s", accessories.Version, accessories.Version, mouth.FileName)
+		} else if mouth.since() == V2 {
+			newer++
+		}
+	}
+	if newer == 0 {
+		t.Error("Expected version 2 natricons to talk with mouths of version 2")
+	}
+}
+
+func TestStillFrameMatchesCombineSVG(t *testing.T) {
+	accessories, _ := GetAccessoriesForHash(benchHash, spc.BTDonor, true, nil)
+	still, _ := CombineSVG(accessories)
+	frame, err := CombineFrameSVG(accessories, Pose{})
+	if err != nil {
+		t.Fatalf("Failed to combine frame %s", err)
+	}
+	if !bytes.Equal(still, frame) {
+		t.Error("Expected frame without pose to equal still image")
+	}
+}
+
+func TestCombineAnimatedSVG(t *testing.T) {
+	accessories, _ := GetAccessoriesForHash(benchHash, spc.BTNone, true, nil)
+	for _, a := range Animations {
+		svg, err := CombineAnimatedSVG(accessories, GetAnimationFrames(accessories, a))
+		if err != nil {
+			t.Fatalf("Failed to combine %s %s", a, err)
+		}
+		// Must be well formed and contain SMIL animations
+		animations := 0
+		decoder := xml.NewDecoder(bytes.NewReader(svg))
+		for {
+			token, err := decoder.Token()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("Invalid SVG for %s %s", a, err)
+			}
+			if el, ok := token.(xml.StartElement); ok && (el.Name.Local == "animate" || el.Name.Local == "animateTransform") {
+				animations++
+			}
+		}
+		if animations == 0 {
+			t.Errorf("Expected SMIL animations for %s", a)
+		}
+	}
+}
@@ -2,10 +2,9 @@ package image
 
 import (
 	"bytes"
-	"encoding/xml"
 	"fmt"
 	"io"
-	"strings"
+	"strconv"
 	"sync"
 
 	svg "github.com/ajstarks/svgo"
@@ -17,161 +16,172 @@ import (
 
 const DefaultSize = 512            // Default SVG width/height attribute
 const lodBwReplacement = "#9CA2AF" // Replace white with this color on bw assets
+const DefaultOutlineWidth = 16.0   // Stroke width of outline assets
 
-type SVG struct {
-	Width  int    `xml:"width,attr"`
-	Height int    `xml:"height,attr"`
-	Doc    string `xml:",innerxml"`
+// CSS variables of themeable natricons
+const (
+	cssVarBody       = "--natricon-body"
+	cssVarHair       = "--natricon-hair"
+	cssVarOutline    = "--natricon-outline"
+	cssVarBackground = "--natricon-background"
+)
+
+// layer - asset drawn as a group of the natricon with its slot replacements
+type layer struct {
+	id     string
+	asset  *Asset
+	values slotValues
+	vars   slotValues // CSS variable of slots, used by themeable natricons
+	attrs  []string   // Extra attributes of the group
+	smil   string     // SMIL animation elements of the group
 }
 
 func CombineSVG(accessories Accessories) ([]byte, error) {
-	var (
-		body         SVG
-		hair         SVG
-		mouth        SVG
-		eye          SVG
-		backHair     SVG
-		bodyOutline  SVG
-		hairOutline  SVG
-		mouthOutline SVG
-		badgeAsset   SVG
-	)
-	// Parse all SVG assets
-	if err := xml.Unmarshal(accessories.BodyAsset.SVGContents, &body); err != nil {
-		glog.Fatalf("Unable to parse body SVG %v", err)
-		return nil, err
-	}
-	if err := xml.Unmarshal(accessories.HairAsset.SVGContents, &hair); err != nil {
-		glog.Fatalf("Unable to parse hair SVG %v", err)
-		return nil, err
-	}
-	if err := xml.Unmarshal(accessories.MouthAsset.SVGContents, &mouth); err != nil {
-		glog.Fatalf("Unable to parse mouth SVG %v", err)
-		return nil, err
-	}
-	if err := xml.Unmarshal(accessories.EyeAsset.SVGContents, &eye); err != nil {
-		glog.Fatalf("Unable to parse eye SVG %v", err)
-		return nil, err
-	}
-	if accessories.BackHairAsset != nil {
-		if err := xml.Unmarshal(accessories.BackHairAsset.SVGContents, &backHair); err != nil {
-			glog.Fatalf("Unable to parse back hair SVG %v", err)
-			return nil, err
-		}
+	return writeSVG(accessories, buildLayers(accessories))
+}
+
+// buildLayers - get layers of a natricon from bottom to top
+func buildLayers(accessories Accessories) []layer {
+	// Perceved brightness of body used for some manipulations
+	perceivedBrightness := int(accessories.BodyColor.PerceivedBrightness())
+	darkBody := LightToDarkSwitchPoint > perceivedBrightness
+	outlineColor := accessories.OutlineColor.ToHTML(true)
+	outlineValues := slotValues{slotBlack: outlineColor}
+	outlineVars := slotValues{slotBlack: cssVarOutline}
+	if accessories.OutlineWidth != 0 && accessories.OutlineWidth != DefaultOutlineWidth {
+		outlineValues[slotStrokeWidth] = strconv.FormatFloat(accessories.OutlineWidth, 'f', -1, 64)
 	}
+
+	var layers []layer
+	// Outlines
 	if accessories.BodyOutlineAsset != nil {
-		if err := xml.Unmarshal(accessories.BodyOutlineAsset.SVGContents, &bodyOutline); err != nil {
-			glog.Fatalf("Unable to parse body outline SVG %v", err)
-			return nil, err
-		}
-	}
-	if accessories.HairOutlineAsset != nil {
-		if err := xml.Unmarshal(accessories.HairOutlineAsset.SVGContents, &hairOutline); err != nil {
-			glog.Fatalf("Unable to parse hair outline SVG %v", err)
-			return nil, err
-		}
+		layers = append(layers, layer{id: "bodyOutline", asset: accessories.BodyOutlineAsset, values: outlineValues, vars: outlineVars})
 	}
 	if accessories.MouthOutlineAsset != nil {
-		if err := xml.Unmarshal(accessories.MouthOutlineAsset.SVGContents, &mouthOutline); err != nil {
-			glog.Fatalf("Unable to parse mouth outline SVG %v", err)
-			return nil, err
-		}
+		layers = append(layers, layer{id: "mouthOutline", asset: accessories.MouthOutlineAsset, values: outlineValues, vars: outlineVars})
 	}
-	if accessories.BadgeAsset != nil {
-		if err := xml.Unmarshal(accessories.BadgeAsset.SVGContents, &badgeAsset); err != nil {
-			glog.Fatalf("Unable to parse badge SVG %v", err)
-			return nil, err
-		}
+	if accessories.HairOutlineAsset != nil {
+		layers = append(layers, layer{id: "hairOutline", asset: accessories.HairOutlineAsset, values: outlineValues, vars: outlineVars})
 	}
-	// Perceved brightness of body used for some manipulations
-	perceivedBrightness := int(accessories.BodyColor.PerceivedBrightness())
-	// Create new SVG writer
-	var b bytes.Buffer
-	canvas := svg.New(&b)
-	canvas.Startraw(fmt.Sprintf("viewBox=\"0 0 %d %d\"", DefaultSize, DefaultSize))
-	// Add body outline
-	if accessories.BodyOutlineAsset != nil {
-		canvas.Gid("bodyOutline")
-		bodyOutline.Doc = strings.ReplaceAll(bodyOutline.Doc, "black", accessories.OutlineColor.ToHTML(true))
-		io.WriteString(canvas.Writer, bodyOutline.Doc)
-		canvas.Gend()
+	if accessories.HatOutlineAsset != nil {
+		layers = append(layers, layer{id: "hatOutline", asset: accessories.HatOutlineAsset, values: outlineValues, vars: outlineVars})
 	}
-	// Add mouth outline
-	if accessories.MouthOutlineAsset != nil {
-		canvas.Gid("mouthOutline")
-		mouthOutline.Doc = strings.ReplaceAll(mouthOutline.Doc, "black", accessories.OutlineColor.ToHTML(true))
-		io.WriteString(canvas.Writer, mouthOutline.Doc)
-		canvas.Gend()
+	for i, asset := range accessories.OverlayOutlineAssets {
+		layers = append(layers, layer{id: fmt.Sprintf("overlay%dOutline", i), asset: asset, values: outlineValues, vars: outlineVars})
 	}
-	// Add hair outline
-	if accessories.HairOutlineAsset != nil {
-		canvas.Gid("hairOutline")
-		hairOutline.Doc = strings.ReplaceAll(hairOutline.Doc, "black", accessories.OutlineColor.ToHTML(true))
-		io.WriteString(canvas.Writer, hairOutline.Doc)
-		canvas.Gend()
+	// Hair colored slots, shared by back hair, hair and mouth
+	var hairValues slotValues
+	hairVars := slotValues{slotHairColor: cssVarHair, slotMouthColor: cssVarHair}
+	if accessories.HairAsset.HairColored {
+		hairValues[slotHairColor] = accessories.HairColor.ToHTML(true)
+		hairValues[slotMouthColor] = hairValues[slotHairColor]
+		hairValues[slotShadowOpacity] = fmt.Sprintf("%f", GetTargetOpacity(accessories.HairColor))
 	}
-	// Add back hair
+	// Back hair
 	if accessories.BackHairAsset != nil {
-		canvas.Gid("backhair")
-		if accessories.HairAsset.HairColored {
-			backHair.Doc = strings.ReplaceAll(backHair.Doc, "#FF0000", accessories.HairColor.ToHTML(true))
-			backHair.Doc = strings.ReplaceAll(backHair.Doc, "fill-opacity=\"0.15\"", fmt.Sprintf("fill-opacity=\"%f\"", GetTargetOpacity(accessories.HairColor)))
-		}
-		io.WriteString(canvas.Writer, backHair.Doc)
-		canvas.Gend()
+		backHair := layer{id: "backhair", asset: accessories.BackHairAsset, vars: hairVars}
+		backHair.values[slotHairColor] = hairValues[slotHairColor]
+		backHair.values[slotShadowOpacity] = hairValues[slotShadowOpacity]
+		layers = append(layers, backHair)
 	}
-	// Body group
-	canvas.Gid("body")
+	// Body
+	body := layer{id: "body", asset: &accessories.BodyAsset, vars: slotValues{slotBodyColor: cssVarBody}}
 	if accessories.BodyAsset.BodyColored {
-		body.Doc = strings.ReplaceAll(body.Doc, "#00FFFF", accessories.BodyColor.ToHTML(true))
-		body.Doc = strings.ReplaceAll(body.Doc, "fill-opacity=\"0.15\"", fmt.Sprintf("fill-opacity=\"%f\"", GetTargetOpacity(accessories.BodyColor)))
+		body.values[slotBodyColor] = accessories.BodyColor.ToHTML(true)
+		body.values[slotShadowOpacity] = fmt.Sprintf("%f", GetTargetOpacity(accessories.BodyColor))
+	}
+	layers = append(layers, body)
+	// Hair
+	hair := layer{id: "hair", asset: &accessories.HairAsset, vars: hairVars}
+	hair.values[slotHairColor] = hairValues[slotHairColor]
+	hair.values[slotShadowOpacity] = hairValues[slotShadowOpacity]
+	layers = append(layers, hair)
+	// Seasonal overlays
+	for i, asset := range accessories.OverlayAssets {
+		layers = append(layers, layer{id: fmt.Sprintf("overlay%d", i), asset: asset})
+	}
+	// Hat
+	if accessories.HatAsset != nil {
+		layers = append(layers, layer{id: "hat", asset: accessories.HatAsset})
+	}
+	// Mouth and eyes
+	mouth := layer{id: "mouth", asset: &accessories.MouthAsset, vars: hairVars}
+	mouth.values[slotMouthColor] = hairValues[slotMouthColor]
+	mouth.values[slotShadowOpacity] = hairValues[slotShadowOpacity]
+	eye := layer{id: "eye", asset: &accessories.EyeAsset}
+	for _, l := range []*layer{&mouth, &eye} {
+		if darkBody && l.asset.DarkBWColored {
+			l.values[slotWhite] = lodBwReplacement
+		}
+		if darkBody && l.asset.DarkColored {
+			l.values[slotBlack] = "white"
+		} else if !darkBody && l.asset.BLK299 {
+			l.values[slotBlk299Opacity] = fmt.Sprintf("%f", GetBlk299Opacity(accessories.BodyColor))
+		}
 	}
-	io.WriteString(canvas.Writer, body.Doc)
-	canvas.Gend()
-	// Hair Group
-	canvas.Gid("hair")
-	if accessories.HairAsset.HairColored {
-		hair.Doc = strings.ReplaceAll(hair.Doc, "#FF0000", accessories.HairColor.ToHTML(true))
-		hair.Doc = strings.ReplaceAll(hair.Doc, "fill-opacity=\"0.15\"", fmt.Sprintf("fill-opacity=\"%f\"", GetTargetOpacity(accessories.HairColor)))
+	layers = append(layers, mouth, eye)
+	// Glasses
+	if accessories.GlassesAsset != nil {
+		layers = append(layers, layer{id: "glasses", asset: accessories.GlassesAsset})
 	}
-	io.WriteString(canvas.Writer, hair.Doc)
-	canvas.Gend()
-	// Mouth group
-	canvas.Gid("mouth")
-	if accessories.HairAsset.HairColored {
-		mouth.Doc = strings.ReplaceAll(mouth.Doc, "#FFFF00", accessories.HairColor.ToHTML(true))
-		mouth.Doc = strings.ReplaceAll(mouth.Doc, "fill-opacity=\"0.15\"", fmt.Sprintf("fill-opacity=\"%f\"", GetTargetOpacity(accessories.HairColor)))
-	}
-	if LightToDarkSwitchPoint > perceivedBrightness && accessories.MouthAsset.DarkBWColored {
-		mouth.Doc = strings.ReplaceAll(mouth.Doc, "white", lodBwReplacement)
-	}
-	if LightToDarkSwitchPoint > perceivedBrightness && accessories.MouthAsset.DarkColored {
-		mouth.Doc = strings.ReplaceAll(mouth.Doc, "black", "white")
-	} else if perceivedBrightness >= LightToDarkSwitchPoint && accessories.MouthAsset.BLK299 {
-		mouth.Doc = strings.ReplaceAll(mouth.Doc, "fill-opacity=\"0.299\"", fmt.Sprintf("fill-opacity=\"%f\"", GetBlk299Opacity(accessories.BodyColor)))
-	}
-	io.WriteString(canvas.Writer, mouth.Doc)
-	canvas.Gend()
-	// Eye group
-	canvas.Gid("eye")
-	if LightToDarkSwitchPoint > perceivedBrightness && accessories.EyeAsset.DarkBWColored {
-		eye.Doc = strings.ReplaceAll(eye.Doc, "white", lodBwReplacement)
-	}
-	if LightToDarkSwitchPoint > perceivedBrightness && accessories.EyeAsset.DarkColored {
-		eye.Doc = strings.ReplaceAll(eye.Doc, "black", "white")
-	} else if perceivedBrightness >= LightToDarkSwitchPoint && accessories.EyeAsset.BLK299 {
-		eye.Doc = strings.ReplaceAll(eye.Doc, "fill-opacity=\"0.299\"", fmt.Sprintf("fill-opacity=\"%f\"", GetBlk299Opacity(accessories.BodyColor)))
-	}
-	io.WriteString(canvas.Writer, eye.Doc)
-	canvas.Gend()
-	// Badge group
+	// Badge
 	if accessories.BadgeAsset != nil {
-		canvas.Gid("badge")
+		badge := layer{id: "badge", asset: accessories.BadgeAsset}
 		// Change color based on outline
 		if accessories.BodyOutlineAsset != nil {
-			badgeAsset.Doc = strings.ReplaceAll(badgeAsset.Doc, "white", accessories.OutlineColor.ToHTML(true))
+			badge.values[slotWhite] = outlineColor
+			badge.vars[slotWhite] = cssVarOutline
+		}
+		layers = append(layers, badge)
+	}
+	return layers
+}
+
+// writeSVG - write layers on the background and in the shape of the natricon to a minified SVG document
+func writeSVG(accessories Accessories, layers []layer) ([]byte, error) {
+	// Create new SVG writer
+	var b bytes.Buffer
+	canvas := svg.New(&b)
+	vb := cropBox(accessories)
+	if vb == fullBox {
+		canvas.Startraw(fmt.Sprintf("viewBox=\"0 0 %d %d\"", DefaultSize, DefaultSize))
+	} else {
+		canvas.Startraw(fmt.Sprintf("viewBox=\"%s\"", vb.viewBox()))
+	}
+	if accessories.Shape.clipped() {
+		io.WriteString(canvas.Writer, fmt.Sprintf("<defs><clipPath id=\"%s\">%s</clipPath></defs>", shapeClipID, shapeElement(accessories.Shape, vb, "")))
+		canvas.Group(fmt.Sprintf("clip-path=\"url(#%s)\"", shapeClipID))
+	}
+	if accessories.Background != nil {
+		fill := fmt.Sprintf("fill=\"%s\"", accessories.Background.ToHTML(true))
+		if accessories.CSSVars {
+			fill = fmt.Sprintf("style=\"fill:var(%s,%s)\"", cssVarBackground, accessories.Background.ToHTML(true))
+		}
+		io.WriteString(canvas.Writer, shapeElement(accessories.Shape, vb, fill))
+	}
+	for i := range layers {
+		if !accessories.HasPart(layerPart(layers[i].id)) {
+			continue
+		}
+		template, err := layers[i].asset.getTemplate()
+		if err != nil {
+			glog.Errorf("Unable to parse %s SVG %v", layers[i].id, err)
+			return nil, err
+		}
+		if len(layers[i].attrs) > 0 {
+			canvas.Group(append([]string{fmt.Sprintf("id=\"%s\"", layers[i].id)}, layers[i].attrs...)...)
+		} else {
+			canvas.Gid(layers[i].id)
 		}
-		io.WriteString(canvas.Writer, badgeAsset.Doc)
+		io.WriteString(canvas.Writer, layers[i].smil)
+		var vars *slotValues
+		if accessories.CSSVars {
+			vars = &layers[i].vars
+		}
+		template.execute(canvas.Writer, &layers[i].values, vars)
+		canvas.Gend()
+	}
+	if accessories.Shape.clipped() {
 		canvas.Gend()
 	}
 	// End document
@@ -0,0 +1,38 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/appditto/natricon/server/color"
+	"github.com/appditto/natricon/server/spc"
+)
+
+const benchHash = "8c7a2e3a1f0b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5"
+
+func BenchmarkCombineSVG(b *testing.B) {
+	accessories, err := GetAccessoriesForHash(benchHash, spc.BTNone, false, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := CombineSVG(accessories); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCombineSVGOutlineBadge(b *testing.B) {
+	accessories, err := GetAccessoriesForHash(benchHash, spc.BTDonor, true, &color.RGB{R: 255, G: 255, B: 255})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := CombineSVG(accessories); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,13 +1,16 @@
 package image
 
 import (
-	"encoding/json"
+	"crypto/sha256"
 	"fmt"
+	"io/fs"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 
+	"github.com/appditto/natricon/server/assets"
 	"github.com/appditto/natricon/server/spc"
 )
 
@@ -15,23 +18,28 @@ type IllustrationType string
 type Sex string
 
 const (
-	Body         IllustrationType = "body"
-	BodyOutline  IllustrationType = "body-outline"
-	Badge        IllustrationType = "badge"
-	Hair         IllustrationType = "hair-front"
-	HairBack     IllustrationType = "hair-back"
-	HairOutline  IllustrationType = "hair-outline"
-	Mouth        IllustrationType = "mouth"
-	MouthOutline IllustrationType = "mouth-outline"
-	Eye          IllustrationType = "eyes"
-	Male         Sex              = "M"
-	Female       Sex              = "F"
-	Neutral      Sex              = "N"
+	Body           IllustrationType = "body"
+	BodyOutline    IllustrationType = "body-outline"
+	Badge          IllustrationType = "badge"
+	Hair           IllustrationType = "hair-front"
+	HairBack       IllustrationType = "hair-back"
+	HairOutline    IllustrationType = "hair-outline"
+	Mouth          IllustrationType = "mouth"
+	MouthOutline   IllustrationType = "mouth-outline"
+	Eye            IllustrationType = "eyes"
+	Hat            IllustrationType = "hat"
+	HatOutline     IllustrationType = "hat-outline"
+	Glasses        IllustrationType = "glasses"
+	Overlay        IllustrationType = "overlay"
+	OverlayOutline IllustrationType = "overlay-outline"
+	Male           Sex              = "M"
+	Female         Sex              = "F"
+	Neutral        Sex              = "N"
 )
 
 type Asset struct {
 	FileName         string           // File name of asset
-	IllustrationPath string           // Full path of illustration on the file system
+	IllustrationPath string           // Path of illustration in the asset directory
 	Type             IllustrationType // Type of illustration (body, hair, mouth, eye)
 	SVGContents      []byte           // Full contents of SVG asset
 	HairColored      bool             // Whether this asset should be colored the same as hair color
@@ -41,153 +49,188 @@ type Asset struct {
 	DarkColored      bool             // Whether this asset gets adjusted on dark colors
 	DarkBWColored    bool             // Whether this asset has a secondary color adjustmetn on dark backgrounds
 	BLK299           bool             // Opacity replacements for _blk299 assets
+	ManifestID       int              // ID of the asset from its manifest, 0 for badges
+	BadgeType        spc.BadgeType    // Type of badge, badges only
+	BadgeBodies      []int            // IDs of the bodies a badge is placed on, badges only
+	Weight           int              // Relative chance of being picked, 1 when 0
+	Since            Version          // Version of the generation algorithm the asset was added in, V1 when 0
+	template         *svgTemplate     // Pre-compiled SVGContents, set when assets are loaded
+	bounds           *box             // Bounds of the shapes in SVGContents, set when assets are loaded
 }
 
-// getIllustrationPath - get full path of image
-func getIllustrationPath(illustration string, iType IllustrationType) string {
-	wd, err := os.Getwd()
+// ID - numeric ID of asset from its manifest, or from the start of its file name. -1 when it has none
+func (a Asset) ID() int {
+	if a.ManifestID > 0 {
+		return a.ManifestID
+	}
+	id, err := strconv.Atoi(strings.Split(a.FileName, "_")[0])
 	if err != nil {
-		panic("Can't get working directory")
+		id, err = strconv.Atoi(strings.Split(a.FileName, ".")[0])
+		if err != nil {
+			return -1
+		}
+	}
+	return id
+}
+
+// weight - relative chance of the asset being picked
+func (a Asset) weight() int {
+	if a.Weight > 0 {
+		return a.Weight
 	}
+	return 1
+}
+
+// since - version of the generation algorithm the asset was added in
+func (a Asset) since() Version {
+	if a.Since > 0 {
+		return a.Since
+	}
+	return V1
+}
 
-	fPath := path.Join(wd, "assets", "illustrations", string(iType), illustration)
-	if _, err := os.Stat(fPath); err != nil {
-		// File does not exist
-		panic(fmt.Sprintf("File %s does not exist", fPath))
+// getTemplate - get pre-compiled template of asset, compiling it if the asset wasn't loaded by the asset manager
+func (a *Asset) getTemplate() (*svgTemplate, error) {
+	if a.template != nil {
+		return a.template, nil
 	}
-	return fPath
+	return compileTemplate(a.SVGContents)
 }
 
-// getSex - get Sex based on image name
-func getSex(name string) Sex {
-	if strings.Contains(name, "_f") {
-		return Female
-	} else if strings.Contains(name, "_m") {
-		return Male
+// getBounds - bounds of the asset, computed if assets weren't loaded with GetAssets
+func (a *Asset) getBounds() box {
+	if a.bounds != nil {
+		return *a.bounds
 	}
-	return Neutral
+	bounds, err := svgBounds(a.SVGContents)
+	if err != nil {
+		return emptyBox
+	}
+	return bounds
 }
 
 // Singleton to keep assets loaded in memory
 type assetManager struct {
-	bodyAssets         []Asset
-	bodyOutlineAssets  []Asset
-	donorBadgeAssets   []Asset
-	exchBadgeAssets    []Asset
-	nodeBadgeAssets    []Asset
-	svcBadgeAssets     []Asset
-	hairAssets         []Asset
-	hairBackAssets     []Asset
-	hairOutlineAssets  []Asset
-	mouthAssets        []Asset
-	mouthOutlineAssets []Asset
-	eyeAssets          []Asset
+	bodyAssets           []Asset
+	bodyOutlineAssets    []Asset
+	donorBadgeAssets     []Asset
+	exchBadgeAssets      []Asset
+	nodeBadgeAssets      []Asset
+	svcBadgeAssets       []Asset
+	hairAssets           []Asset
+	hairBackAssets       []Asset
+	hairOutlineAssets    []Asset
+	mouthAssets          []Asset
+	mouthOutlineAssets   []Asset
+	eyeAssets            []Asset
+	hatAssets            []Asset
+	hatOutlineAssets     []Asset
+	glassesAssets        []Asset
+	overlayAssets        []Asset
+	overlayOutlineAssets []Asset
+	styles               map[Style]stylePack // Alternate illustration sets, loaded with LoadStyles
+	digest               []byte              // SHA-256 of every manifest and SVG the assets were loaded from
+	cropFrames           map[Crop]cropFrame  // Frame of every crop but the full canvas
 }
 
 var singleton *assetManager
 var once sync.Once
 
-func GetAssets() *assetManager {
-	once.Do(func() {
-		var err error
-		// Load body assets
-		var bodyAssets []Asset
-		for _, ba := range BodyIllustrations {
-			var a Asset
-			err = json.Unmarshal(ba, &a)
-			bodyAssets = append(bodyAssets, a)
-		}
-		// Load body outlines
-		var bodyOutlineAssets []Asset
-		for _, ba := range BodyOutlineIllustrations {
-			var a Asset
-			err = json.Unmarshal(ba, &a)
-			bodyOutlineAssets = append(bodyOutlineAssets, a)
-		}
-		// Load badges
-		var donorBadgeAssets []Asset
-		for _, ba := range DonorBadgeIllustrations {
-			var a Asset
-			err = json.Unmarshal(ba, &a)
-			donorBadgeAssets = append(donorBadgeAssets, a)
-		}
-		var exchBadgeAssets []Asset
-		for _, ba := range ExchangeBadgeIllustrations {
-			var a Asset
-			err = json.Unmarshal(ba, &a)
-			exchBadgeAssets = append(exchBadgeAssets, a)
-		}
-		var nodeBadgeAssets []Asset
-		for _, ba := range NodeBadgeIllustrations {
-			var a Asset
-			err = json.Unmarshal(ba, &a)
-			nodeBadgeAssets = append(nodeBadgeAssets, a)
-		}
-		var svcBadgeAssets []Asset
-		for _, ba := range ServiceBadgeIllustrations {
-			var a Asset
-			err = json.Unmarshal(ba, &a)
-			svcBadgeAssets = append(svcBadgeAssets, a)
-		}
-		// Load hair assets
-		var hairAssets []Asset
-		for _, ha := range HairIllustrations {
-			var a Asset
-			err = json.Unmarshal(ha, &a)
-			hairAssets = append(hairAssets, a)
-		}
-		// Load hair back assets
-		var hairBackAssets []Asset
-		for _, ha := range HairBackIllustrations {
-			var a Asset
-			err = json.Unmarshal(ha, &a)
-			hairBackAssets = append(hairBackAssets, a)
+// prepare - pre-compile template and measure bounds of the asset SVG
+func (a *Asset) prepare() error {
+	template, err := compileTemplate(a.SVGContents)
+	if err != nil {
+		return fmt.Errorf("Failed to parse SVG of asset %s: %s", a.FileName, err)
+	}
+	a.template = template
+	bounds, err := svgBounds(a.SVGContents)
+	if err != nil {
+		return fmt.Errorf("Failed to measure SVG of asset %s: %s", a.FileName, err)
+	}
+	a.bounds = &bounds
+	return nil
+}
+
+// loadAssets - load and validate every illustration type from an asset directory, in manifest order
+func loadAssets(fsys fs.FS) (*assetManager, error) {
+	if err := ValidateManifests(fsys); err != nil {
+		return nil, err
+	}
+	loaded := map[IllustrationType][]Asset{}
+	hasher := sha256.New()
+	for _, iType := range illustrationTypes {
+		manifest, err := fs.ReadFile(fsys, path.Join(string(iType), ManifestFile))
+		if err != nil {
+			return nil, err
 		}
-		// Hair outline
-		var hairOutlineAssets []Asset
-		for _, ha := range HairOutlineIllustrations {
-			var a Asset
-			err = json.Unmarshal(ha, &a)
-			hairOutlineAssets = append(hairOutlineAssets, a)
+		writeDigestPart(hasher, string(iType), manifest)
+		typeAssets, err := LoadManifestAssets(fsys, iType)
+		if err != nil {
+			return nil, err
 		}
-		// Load mouth assets
-		var mouthAssets []Asset
-		for _, ma := range MouthIllustrations {
-			var a Asset
-			err = json.Unmarshal(ma, &a)
-			mouthAssets = append(mouthAssets, a)
+		for i := range typeAssets {
+			if err := typeAssets[i].prepare(); err != nil {
+				return nil, err
+			}
+			writeDigestPart(hasher, typeAssets[i].IllustrationPath, typeAssets[i].SVGContents)
 		}
-		// Mouth Outline
-		var mouthOutlineAssets []Asset
-		for _, ma := range MouthOutlineIllustrations {
-			var a Asset
-			err = json.Unmarshal(ma, &a)
-			mouthOutlineAssets = append(mouthOutlineAssets, a)
+		loaded[iType] = typeAssets
+	}
+	badges := func(btype spc.BadgeType) []Asset {
+		var ret []Asset
+		for _, a := range loaded[Badge] {
+			if a.BadgeType == btype {
+				ret = append(ret, a)
+			}
 		}
-		// Load eye assets
-		var eyeAssets []Asset
-		for _, ea := range EyeIllustrations {
-			var a Asset
-			err = json.Unmarshal(ea, &a)
-			eyeAssets = append(eyeAssets, a)
+		return ret
+	}
+	return &assetManager{
+		bodyAssets:           loaded[Body],
+		bodyOutlineAssets:    loaded[BodyOutline],
+		donorBadgeAssets:     badges(spc.BTDonor),
+		exchBadgeAssets:      badges(spc.BTExchange),
+		nodeBadgeAssets:      badges(spc.BTNode),
+		svcBadgeAssets:       badges(spc.BTService),
+		hairAssets:           loaded[Hair],
+		hairBackAssets:       loaded[HairBack],
+		hairOutlineAssets:    loaded[HairOutline],
+		mouthAssets:          loaded[Mouth],
+		mouthOutlineAssets:   loaded[MouthOutline],
+		eyeAssets:            loaded[Eye],
+		hatAssets:            loaded[Hat],
+		hatOutlineAssets:     loaded[HatOutline],
+		glassesAssets:        loaded[Glasses],
+		overlayAssets:        loaded[Overlay],
+		overlayOutlineAssets: loaded[OverlayOutline],
+		digest:               hasher.Sum(nil),
+		cropFrames:           newCropFrames(loaded),
+	}, nil
+}
+
+// LoadAssets - use illustrations from a directory laid out like assets/illustrations instead of the built-in ones
+// Must be called before natricons are generated and styles are loaded
+func LoadAssets(dir string) error {
+	sm, err := loadAssets(os.DirFS(dir))
+	if err != nil {
+		return fmt.Errorf("Failed to load assets from %s: %s", dir, err)
+	}
+	singleton = sm
+	return nil
+}
+
+// GetAssets - get loaded assets, the built-in illustrations unless LoadAssets was called
+func GetAssets() *assetManager {
+	once.Do(func() {
+		if singleton != nil {
+			return
 		}
+		illustrations, err := fs.Sub(assets.Illustrations, "illustrations")
 		if err != nil {
-			panic("Failed to decode assets")
+			panic(err.Error())
 		}
-		// Create object
-		singleton = &assetManager{
-			bodyAssets:         bodyAssets,
-			bodyOutlineAssets:  bodyOutlineAssets,
-			donorBadgeAssets:   donorBadgeAssets,
-			exchBadgeAssets:    exchBadgeAssets,
-			nodeBadgeAssets:    nodeBadgeAssets,
-			svcBadgeAssets:     svcBadgeAssets,
-			hairAssets:         hairAssets,
-			hairBackAssets:     hairBackAssets,
-			hairOutlineAssets:  hairOutlineAssets,
-			mouthAssets:        mouthAssets,
-			mouthOutlineAssets: mouthOutlineAssets,
-			eyeAssets:          eyeAssets,
+		if singleton, err = loadAssets(illustrations); err != nil {
+			panic(err.Error())
 		}
 	})
 	return singleton
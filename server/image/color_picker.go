@@ -2,10 +2,8 @@ package image
 
 import (
 	"math"
-	"strconv"
 
 	"github.com/appditto/natricon/server/color"
-	"github.com/appditto/natricon/server/rand"
 )
 
 // Min and max perceivedBrightness values (between 0 and 100)
@@ -43,34 +41,15 @@ const LightToDarkSwitchPoint = 30
 const hairBrightnessDynamicMax = 90.0
 const hairSaturationDynamicMin = 10.0
 
-// GetBodyColor - Get body color with given entropy
-func GetBodyColor(entropy string) (color.RGB, error) {
+// GetBodyColor - Get body color with a random source for each channel
+func GetBodyColor(red randSource, green randSource, blue randSource) color.RGB {
 	// Want to generate hue between 0-360
-	// Get detemrinistic RNG
-	randSeed, err := strconv.ParseInt(entropy[0:4], 16, 64)
-	if err != nil {
-		return color.RGB{}, err
-	}
 	outRGB := color.RGB{}
 	// Generate R between 0..255
-	r := rand.Init()
-	r.Seed(uint32(randSeed))
-	outRGB.R = float64(r.Int31n(255*1000)) / 1000
+	outRGB.R = float64(red.Int31n(255*1000)) / 1000
 	// Generate G between 0.255
-	randSeed, err = strconv.ParseInt(entropy[4:8], 16, 64)
-	if err != nil {
-		return color.RGB{}, err
-	}
-	r = rand.Init()
-	r.Seed(uint32(randSeed))
-	outRGB.G = float64(r.Int31n(255*1000)) / 1000
+	outRGB.G = float64(green.Int31n(255*1000)) / 1000
 	// Generate Blue
-	randSeed, err = strconv.ParseInt(entropy[8:12], 16, 64)
-	if err != nil {
-		return color.RGB{}, err
-	}
-	r = rand.Init()
-	r.Seed(uint32(randSeed))
 	lowerBound := math.Max(
 		math.Sqrt(
 			math.Max(
@@ -89,31 +68,21 @@ func GetBodyColor(entropy string) (color.RGB, error) {
 		),
 		255.0,
 	) * 1000
-	outRGB.B = (float64(r.Int31n(int32(upperBound)-int32(lowerBound))) + lowerBound) / 1000
+	outRGB.B = (float64(blue.Int31n(int32(upperBound)-int32(lowerBound))) + lowerBound) / 1000
 
-	return outRGB, nil
+	return outRGB
 }
 
-// GetHairColor - Get a complementary color with given entropy
-func GetHairColor(bodyColor color.RGB, hEntropy string, sEntropy string, bEntropy string) (color.RGB, error) {
-	var err error
+// GetHairColor - Get a complementary color with a random source for hue, saturation and brightness
+func GetHairColor(bodyColor color.RGB, hue randSource, saturation randSource, brightness randSource) color.RGB {
 	// Get as HSB color
 	bodyColorHSB := bodyColor.ToHSB()
 
-	var randSeed int64
 	// Want to shift the hue between 90-270
-	// Get detemrinistic RNG
-	randSeed, err = strconv.ParseInt(hEntropy, 16, 64)
-	if err != nil {
-		return color.RGB{}, err
-	}
-
 	// Generate random shift between <minDistance>...270
-	r := rand.Init()
-	r.Seed(uint32(randSeed))
 	lowerBound := bodyColorHSB.H - 180 - BodyAndHairHueDistance
 	upperBound := bodyColorHSB.H - 180 + BodyAndHairHueDistance
-	H := (float64(r.Int31n(int32(upperBound*1000)-int32(lowerBound*1000))) + lowerBound*1000) / 1000
+	H := (float64(hue.Int31n(int32(upperBound*1000)-int32(lowerBound*1000))) + lowerBound*1000) / 1000
 
 	// If < 0 normalize
 	if H < 0 {
@@ -121,23 +90,11 @@ func GetHairColor(bodyColor color.RGB, hEntropy string, sEntropy string, bEntrop
 	}
 
 	// Generate saturation
-	randSeed, err = strconv.ParseInt(sEntropy, 16, 64)
-	if err != nil {
-		return color.RGB{}, err
-	}
-	r = rand.Init()
-	r.Seed(uint32(randSeed))
 	// When body saturation is high enough, hair saturation can end up being less than 0 here, so we're making sure that hair saturation's minimum value never goes below 0v
 	lowerSBound := int32(math.Max(MinTotalSaturation-bodyColorHSB.S*100.0, 0) * 1000)
-	S := float64(r.Int31n(100*1000-lowerSBound)+lowerSBound) / (100.0 * 1000.0)
+	S := float64(saturation.Int31n(100*1000-lowerSBound)+lowerSBound) / (100.0 * 1000.0)
 
 	// Generate random brightess between MinimumBrightness - 100
-	randSeed, err = strconv.ParseInt(bEntropy, 16, 64)
-	if err != nil {
-		return color.RGB{}, err
-	}
-	r = rand.Init()
-	r.Seed(uint32(randSeed))
 	// When the perceived brightness of body is low enough, hair brightness can end up being more than 100 here, so we're making sure that hair brightness's minimum value never goes above 100
 	upperBBound := hairBrightnessDynamicMax
 	if S*100 > hairSaturationDynamicMin {
@@ -147,10 +104,10 @@ func GetHairColor(bodyColor color.RGB, hEntropy string, sEntropy string, bEntrop
 	// Allow more precision for RNG
 	upperBBound *= 1000
 	lowerBBound *= 1000
-	B := float64(r.Int31n(int32(upperBBound)-int32(lowerBBound))+int32(lowerBBound)) / (100 * 1000)
+	B := float64(brightness.Int31n(int32(upperBBound)-int32(lowerBBound))+int32(lowerBBound)) / (100 * 1000)
 	return color.HSB{
 		H: H,
 		S: S,
 		B: B,
-	}.ToRGB(), nil
+	}.ToRGB()
 }
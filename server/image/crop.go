@@ -0,0 +1,258 @@
+package image
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"golang.org/x/image/math/fixed"
+)
+
+// Crop - part of the natricon the view box is framed around
+type Crop string
+
+const (
+	CropFull Crop = "full" // The whole canvas
+	CropBust Crop = "bust" // Around the head, from the top of every hair down to the bottom of every body
+	CropFace Crop = "face" // Around every eye and mouth
+)
+
+// Crops - every supported crop
+var Crops = []Crop{CropFull, CropBust, CropFace}
+
+const bustPadding = 0.04 // Padding around bust crops, relative to the cropped size
+const facePadding = 0.15 // Padding around face crops, relative to the cropped size
+
+// ParseCrop - get crop by name, ok is false if the crop doesn't exist
+func ParseCrop(name string) (Crop, bool) {
+	for _, c := range Crops {
+		if string(c) == strings.ToLower(name) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// box - axis aligned rectangle in natricon coordinates
+type box struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// emptyBox - box containing nothing, the identity of union
+var emptyBox = box{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+
+// fullBox - the whole canvas
+var fullBox = box{MaxX: DefaultSize, MaxY: DefaultSize}
+
+func (b box) empty() bool {
+	return b.MaxX < b.MinX || b.MaxY < b.MinY
+}
+
+func (b box) width() float64 {
+	return b.MaxX - b.MinX
+}
+
+func (b box) height() float64 {
+	return b.MaxY - b.MinY
+}
+
+func (b box) union(o box) box {
+	return box{
+		MinX: math.Min(b.MinX, o.MinX),
+		MinY: math.Min(b.MinY, o.MinY),
+		MaxX: math.Max(b.MaxX, o.MaxX),
+		MaxY: math.Max(b.MaxY, o.MaxY),
+	}
+}
+
+func (b box) addPoint(x, y float64) box {
+	return b.union(box{MinX: x, MinY: y, MaxX: x, MaxY: y})
+}
+
+// grow - grow box by d on every side
+func (b box) grow(d float64) box {
+	if b.empty() {
+		return b
+	}
+	return box{MinX: b.MinX - d, MinY: b.MinY - d, MaxX: b.MaxX + d, MaxY: b.MaxY + d}
+}
+
+// top - top edge of the box without its width, unions extend up to it without getting wider
+func (b box) top() box {
+	if b.empty() {
+		return b
+	}
+	return box{MinX: math.Inf(1), MinY: b.MinY, MaxX: math.Inf(-1), MaxY: b.MinY}
+}
+
+// clamp - move box inside of the canvas, as far as it fits
+func (b box) clamp() box {
+	dx := math.Max(fullBox.MinX-b.MinX, 0) + math.Min(fullBox.MaxX-b.MaxX, 0)
+	dy := math.Max(fullBox.MinY-b.MinY, 0) + math.Min(fullBox.MaxY-b.MaxY, 0)
+	return box{MinX: b.MinX + dx, MinY: b.MinY + dy, MaxX: b.MaxX + dx, MaxY: b.MaxY + dy}
+}
+
+// square - smallest square around the center of the box, with padding relative to its size
+func (b box) square(padding float64) box {
+	side := math.Max(b.width(), b.height()) / (1 - 2*padding)
+	cx, cy := (b.MinX+b.MaxX)/2, (b.MinY+b.MaxY)/2
+	return box{MinX: cx - side/2, MinY: cy - side/2, MaxX: cx + side/2, MaxY: cy + side/2}
+}
+
+// viewBox - value of the viewBox attribute framing the box
+func (b box) viewBox() string {
+	return fmt.Sprintf("%s %s %s %s", formatNumber(b.MinX), formatNumber(b.MinY), formatNumber(b.width()), formatNumber(b.height()))
+}
+
+// boundsAdder - rasterx adder that collects the bounds of every point of a path
+// Control points are included, so curves may be slightly overestimated
+type boundsAdder struct {
+	bounds box
+}
+
+func (ba *boundsAdder) add(points ...fixed.Point26_6) {
+	for _, p := range points {
+		ba.bounds = ba.bounds.addPoint(float64(p.X)/64, float64(p.Y)/64)
+	}
+}
+
+func (ba *boundsAdder) Start(a fixed.Point26_6)            { ba.add(a) }
+func (ba *boundsAdder) Line(b fixed.Point26_6)             { ba.add(b) }
+func (ba *boundsAdder) QuadBezier(b, c fixed.Point26_6)    { ba.add(b, c) }
+func (ba *boundsAdder) CubeBezier(b, c, d fixed.Point26_6) { ba.add(b, c, d) }
+func (ba *boundsAdder) Stop(closeLoop bool)                {}
+
+// svgBounds - bounds of every shape of an asset SVG, strokes excluded
+func svgBounds(svgContents []byte) (box, error) {
+	ba := &boundsAdder{bounds: emptyBox}
+	decoder := xml.NewDecoder(bytes.NewReader(svgContents))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return ba.bounds, nil
+		} else if err != nil {
+			return emptyBox, err
+		}
+		el, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch el.Name.Local {
+		case "path":
+			cursor := &oksvg.PathCursor{}
+			if err := cursor.CompilePath(getAttr(el, "d")); err != nil {
+				return emptyBox, err
+			}
+			cursor.Path.AddTo(ba)
+		case "rect":
+			x, y := getFloatAttr(el, "x"), getFloatAttr(el, "y")
+			ba.bounds = ba.bounds.addPoint(x, y).addPoint(x+getFloatAttr(el, "width"), y+getFloatAttr(el, "height"))
+		case "circle":
+			cx, cy, r := getFloatAttr(el, "cx"), getFloatAttr(el, "cy"), getFloatAttr(el, "r")
+			ba.bounds = ba.bounds.addPoint(cx-r, cy-r).addPoint(cx+r, cy+r)
+		case "ellipse":
+			cx, cy, rx, ry := getFloatAttr(el, "cx"), getFloatAttr(el, "cy"), getFloatAttr(el, "rx"), getFloatAttr(el, "ry")
+			ba.bounds = ba.bounds.addPoint(cx-rx, cy-ry).addPoint(cx+rx, cy+ry)
+		}
+	}
+}
+
+func getAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func getFloatAttr(el xml.StartElement, name string) float64 {
+	f, _ := strconv.ParseFloat(getAttr(el, name), 64)
+	return f
+}
+
+// Illustration types every crop is framed around
+var cropTypes = map[Crop][]IllustrationType{
+	CropBust: {Body, BodyOutline},
+	CropFace: {Eye, Mouth},
+}
+
+// Illustration types crops leave room above for, without getting wider than the types they're framed around
+// Wide hair would zoom bust crops out to almost the whole canvas, it's cut off at the sides instead
+var cropHeadroomTypes = map[Crop][]IllustrationType{
+	CropBust: {Hair, HairBack, HairOutline},
+}
+
+// Outline illustration types, half of their stroke is outside of their paths
+var outlineTypes = []IllustrationType{BodyOutline, HairOutline, MouthOutline, HatOutline, OverlayOutline}
+
+// cropFrame - union of the bounds of every asset a crop is framed around, outlines apart as their stroke width varies
+type cropFrame struct {
+	shapes   box
+	outlines box
+}
+
+// newCropFrames - frame of every crop around all of the loaded assets, so every natricon is cropped at the same scale
+// Style packs mirror the built-in assets and are framed like them
+func newCropFrames(loaded map[IllustrationType][]Asset) map[Crop]cropFrame {
+	frames := map[Crop]cropFrame{}
+	for crop, types := range cropTypes {
+		frame := cropFrame{shapes: emptyBox, outlines: emptyBox}
+		add := func(iType IllustrationType, headroom bool) {
+			for i := range loaded[iType] {
+				bounds := loaded[iType][i].getBounds()
+				if headroom {
+					bounds = bounds.top()
+				}
+				if outlineType(iType) {
+					frame.outlines = frame.outlines.union(bounds)
+				} else {
+					frame.shapes = frame.shapes.union(bounds)
+				}
+			}
+		}
+		for _, iType := range types {
+			add(iType, false)
+		}
+		for _, iType := range cropHeadroomTypes[crop] {
+			add(iType, true)
+		}
+		frames[crop] = frame
+	}
+	return frames
+}
+
+func outlineType(iType IllustrationType) bool {
+	for _, t := range outlineTypes {
+		if t == iType {
+			return true
+		}
+	}
+	return false
+}
+
+// cropBox - area of the canvas shown for the crop of the natricon, the same for every natricon with the same options
+func cropBox(accessories Accessories) box {
+	frame, ok := GetAssets().cropFrames[accessories.Crop]
+	if !ok {
+		return fullBox
+	}
+	width := accessories.OutlineWidth
+	if width == 0 {
+		width = DefaultOutlineWidth
+	}
+	bounds := frame.shapes.union(frame.outlines.grow(width / 2))
+	if bounds.empty() {
+		return fullBox
+	}
+	padding := bustPadding
+	if accessories.Crop == CropFace {
+		padding = facePadding
+	}
+	return bounds.square(padding).clamp()
+}
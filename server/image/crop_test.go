@@ -0,0 +1,82 @@
+package image
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/appditto/natricon/server/spc"
+)
+
+func TestSVGBounds(t *testing.T) {
+	bounds, err := svgBounds([]byte(`<svg viewBox="0 0 512 512"><path d="M128 176H384V384H128z"/><circle cx="256" cy="100" r="20"/></svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds != (box{MinX: 128, MinY: 80, MaxX: 384, MaxY: 384}) {
+		t.Errorf("Unexpected bounds %v", bounds)
+	}
+}
+
+func TestCropBox(t *testing.T) {
+	accessories, err := GetAccessoriesForHash(benchHash, spc.BTNone, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vb := cropBox(accessories); vb != fullBox {
+		t.Errorf("Expected full canvas without crop but got %v", vb)
+	}
+	accessories.Crop = CropBust
+	bust := cropBox(accessories)
+	accessories.Crop = CropFace
+	face := cropBox(accessories)
+	if bust.width() != bust.height() || face.width() != face.height() {
+		t.Errorf("Expected square crops but got %v %v", bust, face)
+	}
+	if face.width() >= bust.width() || bust.width() >= DefaultSize {
+		t.Errorf("Expected face crop inside of bust crop inside of canvas but got %v %v", face, bust)
+	}
+	svg, err := CombineSVG(accessories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(svg, []byte("viewBox=\""+face.viewBox()+"\"")) {
+		t.Errorf("Expected view box %s in %s", face.viewBox(), svg[:100])
+	}
+}
+
+func TestCropBoxSameScale(t *testing.T) {
+	for _, crop := range []Crop{CropBust, CropFace} {
+		boxes := map[string]box{}
+		for i := 0; i < 500; i++ {
+			accessories, err := GetAccessoriesForHash(sampleHash(i), spc.BTDonor, true, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			accessories.Crop = crop
+			boxes[accessories.BodyAsset.FileName] = cropBox(accessories)
+		}
+		if len(boxes) != len(GetAssets().bodyAssets) {
+			t.Fatalf("Expected samples with each of the %d bodies got %d", len(GetAssets().bodyAssets), len(boxes))
+		}
+		first := boxes[GetAssets().bodyAssets[0].FileName]
+		for body, b := range boxes {
+			if b != first {
+				t.Errorf("Expected %s crop of %s to be %v like every body got %v", crop, body, first, b)
+			}
+		}
+		// Bust crops show every body, clearly zoomed in on the canvas
+		if crop == CropBust {
+			for _, a := range GetAssets().bodyAssets {
+				if bounds := a.getBounds().union(first); bounds != first {
+					t.Errorf("Expected %s inside of the bust crop %v", a.FileName, first)
+				}
+			}
+			if first.width() > 0.85*DefaultSize {
+				t.Errorf("Expected the bust crop to be clearly smaller than the canvas got %v", first)
+			}
+		}
+		if first.union(fullBox) != fullBox {
+			t.Errorf("Expected the %s crop %v inside of the canvas", crop, first)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"sort"
+)
+
+// writeDigestPart - write a named part to a content digest, length prefixed so parts can't bleed into each other
+func writeDigestPart(h hash.Hash, name string, data []byte) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(name)))
+	h.Write(length[:])
+	h.Write([]byte(name))
+	binary.BigEndian.PutUint64(length[:], uint64(len(data)))
+	h.Write(length[:])
+	h.Write(data)
+}
+
+// ContentDigest - hex SHA-256 of the loaded illustrations, style packs and seasons config
+// Rendered natricons depend on all of them, so caches key renders with it to drop them when any changes
+func ContentDigest() string {
+	sm := GetAssets()
+	hasher := sha256.New()
+	writeDigestPart(hasher, "illustrations", sm.digest)
+	for _, style := range Styles()[1:] {
+		pack := sm.styles[style]
+		for _, iType := range illustrationTypes {
+			var names []string
+			for name := range pack[iType] {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				writeDigestPart(hasher, string(style)+"/"+string(iType)+"/"+name, pack[iType][name].SVGContents)
+			}
+		}
+	}
+	writeDigestPart(hasher, "seasons", seasonsData)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
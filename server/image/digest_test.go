@@ -0,0 +1,38 @@
+package image
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+func TestContentDigest(t *testing.T) {
+	fsys := copyIllustrations(t)
+	original, err := loadAssets(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, _ := loadAssets(fsys)
+	if !bytes.Equal(original.digest, reloaded.digest) {
+		t.Errorf("Expected the same assets to have the same digest")
+	}
+
+	// Any changed SVG or manifest changes the digest
+	name := "eyes/1_blk29_e.svg"
+	fsys[name] = &fstest.MapFile{Data: bytes.Replace(fsys[name].Data, []byte("<path"), []byte(`<path opacity="0.5"`), 1)}
+	changed, err := loadAssets(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(original.digest, changed.digest) {
+		t.Errorf("Expected a changed SVG to change the digest")
+	}
+
+	digest := ContentDigest()
+	saved := seasonsData
+	seasonsData = []byte(`{"seasons":[]}`)
+	defer func() { seasonsData = saved }()
+	if ContentDigest() == digest {
+		t.Errorf("Expected a changed seasons config to change the digest")
+	}
+}
@@ -0,0 +1,133 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/appditto/natricon/server/rand"
+)
+
+// randSource - source of the random numbers a trait is picked with
+type randSource interface {
+	// Int31n - random number in [0, n)
+	Int31n(n int32) int32
+}
+
+// seededRand - MT19937 seeded with a few hex digits of the hash, how version 1 derives every trait
+func seededRand(entropy string) (randSource, error) {
+	randSeed, err := strconv.ParseInt(entropy, 16, 64)
+	if err != nil {
+		return nil, err
+	}
+	r := rand.Init()
+	r.Seed(uint32(randSeed))
+	return r, nil
+}
+
+// traitStream - stream of random numbers for one trait, derived from the whole hash
+// Block i of the stream is SHA-256(label || 0x00 || hash || uint32 big endian i), hash being the 32 decoded bytes
+// Every trait has its own label, so traits are independent of each other and use all of the hash
+type traitStream struct {
+	label   string
+	hash    []byte
+	counter uint32
+	block   []byte // Unread bytes of the current block
+}
+
+// newTraitStream - stream of a trait for a 64-character hex hash
+func newTraitStream(hash string, label string) (*traitStream, error) {
+	decoded, err := hex.DecodeString(hash)
+	if err != nil || len(decoded) != sha256.Size {
+		return nil, fmt.Errorf("Invalid hash")
+	}
+	return &traitStream{label: label, hash: decoded}, nil
+}
+
+// Uint32 - next 4 bytes of the stream as a big endian number
+func (s *traitStream) Uint32() uint32 {
+	if len(s.block) < 4 {
+		hasher := sha256.New()
+		hasher.Write([]byte(s.label))
+		hasher.Write([]byte{0})
+		hasher.Write(s.hash)
+		var counter [4]byte
+		binary.BigEndian.PutUint32(counter[:], s.counter)
+		hasher.Write(counter[:])
+		s.block = hasher.Sum(nil)
+		s.counter++
+	}
+	v := binary.BigEndian.Uint32(s.block)
+	s.block = s.block[4:]
+	return v
+}
+
+// Int31n - random number in [0, n) without modulo bias
+// Numbers at or above the largest multiple of n that fits in 32 bits are rejected and drawn again
+// Returns 0 when the range is empty, like MT19937 does
+func (s *traitStream) Int31n(n int32) int32 {
+	if n <= 0 {
+		return 0
+	}
+	limit := (1 << 32) - (1<<32)%uint64(n)
+	for {
+		if v := uint64(s.Uint32()); v < limit {
+			return int32(v % uint64(n))
+		}
+	}
+}
+
+// traitSources - random sources of every trait picked for a natricon
+type traitSources struct {
+	bodyRed, bodyGreen, bodyBlue            randSource
+	hairHue, hairSaturation, hairBrightness randSource
+	body, hair, mouth, eye                  randSource
+}
+
+// Labels of the trait streams of version 2
+const traitLabelPrefix = "natricon/v2/"
+
+// traitSources - random sources of a hash, following the rules of the version
+// Version 1 seeds MT19937 with up to 9 hex digits per trait, the hash must be validated before
+func (v Version) traitSources(hash string) (traitSources, error) {
+	var src traitSources
+	targets := []*randSource{&src.bodyRed, &src.bodyGreen, &src.bodyBlue, &src.hairHue, &src.hairSaturation, &src.hairBrightness, &src.body, &src.hair, &src.mouth, &src.eye}
+	if !algorithms[v].streams {
+		// Hex digits of the hash each trait is seeded with
+		ranges := [][2]int{{0, 4}, {4, 8}, {8, 12}, {16, 26}, {26, 30}, {30, 34}, {34, 40}, {40, 46}, {46, 55}, {55, 64}}
+		for i, target := range targets {
+			r, err := seededRand(hash[ranges[i][0]:ranges[i][1]])
+			if err != nil {
+				return src, err
+			}
+			*target = r
+		}
+		return src, nil
+	}
+	labels := []string{"body-color/red", "body-color/green", "body-color/blue", "hair-color/hue", "hair-color/saturation", "hair-color/brightness", "body", "hair", "mouth", "eye"}
+	for i, target := range targets {
+		s, err := newTraitStream(hash, traitLabelPrefix+labels[i])
+		if err != nil {
+			return src, err
+		}
+		*target = s
+	}
+	return src, nil
+}
+
+// accessorySource - random source of an accessory layer type, the index of the type in accessoryTypes
+// Version 1 seeds MT19937 with 8 hex digits of the hash hashed again, so accessories don't follow other traits
+// The 64 hex digits of the digest only cover 8 accessory types, more need version 2
+func (v Version) accessorySource(hash string, index int, iType IllustrationType) (randSource, error) {
+	if !algorithms[v].streams {
+		digest := sha256.Sum256([]byte(hash))
+		entropy := hex.EncodeToString(digest[:])
+		if index < 0 || (index+1)*8 > len(entropy) {
+			return nil, fmt.Errorf("Version %d has entropy for %d accessory types, %s is number %d", v, len(entropy)/8, iType, index+1)
+		}
+		return seededRand(entropy[index*8 : (index+1)*8])
+	}
+	return newTraitStream(hash, traitLabelPrefix+"accessory/"+string(iType))
+}
@@ -0,0 +1,68 @@
+package image
+
+import (
+	"testing"
+)
+
+const entropyTestHash = "5e7f1e8f2ad6dc3ac3a8f4ec0c4d0ad1d7e59c4bcd2f4e1d0e3c0be9bf8a1c22"
+
+func TestTraitStreamDeterministic(t *testing.T) {
+	a, _ := newTraitStream(entropyTestHash, "natricon/v2/body")
+	b, _ := newTraitStream(entropyTestHash, "natricon/v2/body")
+	// Read past the first block to cover the counter
+	for i := 0; i < 20; i++ {
+		if x, y := a.Uint32(), b.Uint32(); x != y {
+			t.Fatalf("Expected the same stream but number %d is %d and %d", i, x, y)
+		}
+	}
+}
+
+func TestTraitStreamLabelsSeparated(t *testing.T) {
+	body, _ := newTraitStream(entropyTestHash, "natricon/v2/body")
+	hair, _ := newTraitStream(entropyTestHash, "natricon/v2/hair")
+	same := 0
+	for i := 0; i < 8; i++ {
+		if body.Uint32() == hair.Uint32() {
+			same++
+		}
+	}
+	if same == 8 {
+		t.Errorf("Expected streams with different labels to differ")
+	}
+}
+
+func TestTraitStreamInt31n(t *testing.T) {
+	s, _ := newTraitStream(entropyTestHash, "natricon/v2/eye")
+	for _, n := range []int32{1, 2, 3, 7, 255 * 1000, 1<<31 - 1} {
+		for i := 0; i < 100; i++ {
+			if v := s.Int31n(n); v < 0 || v >= n {
+				t.Fatalf("Int31n(%d) returned %d", n, v)
+			}
+		}
+	}
+	if v := s.Int31n(0); v != 0 {
+		t.Errorf("Expected an empty range to return 0 but got %d", v)
+	}
+}
+
+func TestTraitStreamInvalidHash(t *testing.T) {
+	for _, hash := range []string{"", "zz", entropyTestHash[:62]} {
+		if _, err := newTraitStream(hash, "natricon/v2/body"); err == nil {
+			t.Errorf("Expected hash %q to be invalid", hash)
+		}
+	}
+}
+
+func TestAccessorySourceLimit(t *testing.T) {
+	for index := 0; index < 8; index++ {
+		if _, err := V1.accessorySource(entropyTestHash, index, Hat); err != nil {
+			t.Errorf("Expected version 1 to have entropy for accessory type %d got %v", index, err)
+		}
+	}
+	if _, err := V1.accessorySource(entropyTestHash, 8, Hat); err == nil {
+		t.Errorf("Expected an error past the entropy of version 1")
+	}
+	if _, err := V2.accessorySource(entropyTestHash, 8, Hat); err != nil {
+		t.Errorf("Expected version 2 to have entropy for any accessory type got %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package image
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/appditto/natricon/server/spc"
+)
+
+// ManifestFile - name of the manifest in every illustration directory
+const ManifestFile = "manifest.json"
+
+// Tint colors and dark mode adjustments of manifest entries
+const (
+	TintBody  = "body"
+	TintHair  = "hair"
+	DarkBlack = "black" // Black is replaced with white on dark bodies
+	DarkWhite = "white" // White is replaced with lodBwReplacement on dark bodies
+)
+
+// Manifest - metadata of the assets of an illustration type, in the order natricons pick them from
+type Manifest struct {
+	Assets []ManifestEntry `json:"assets"`
+}
+
+// ManifestEntry - metadata of an asset
+type ManifestEntry struct {
+	File        string        `json:"file"`
+	ID          int           `json:"id,omitempty"`           // Required for every type but badges
+	Sex         Sex           `json:"sex"`                    // M, F or N
+	Tint        []string      `json:"tint,omitempty"`         // Colors the asset is tinted with, body or hair
+	LightOnly   bool          `json:"light_only,omitempty"`   // Only picked for light bodies
+	DarkMode    []string      `json:"dark_mode,omitempty"`    // Colors adjusted on dark bodies, black or white
+	BLK299      bool          `json:"blk299,omitempty"`       // Opacity of fill-opacity="0.299" slots depends on the body
+	Badge       spc.BadgeType `json:"badge,omitempty"`        // Type of badge, badges only
+	BadgeBodies []int         `json:"badge_bodies,omitempty"` // IDs of the bodies the badge is placed on, badges only
+	Weight      int           `json:"weight,omitempty"`       // Relative chance of being picked, 1 when 0
+	Since       Version       `json:"since,omitempty"`        // Version of the generation algorithm the asset was added in, 1 when 0
+}
+
+// Illustration types with a manifest, in load order
+var illustrationTypes = []IllustrationType{Body, BodyOutline, Badge, Hair, HairBack, HairOutline, Mouth, MouthOutline, Eye, Hat, HatOutline, Glasses, Overlay, OverlayOutline}
+
+// Illustration types whose assets are variants of another type, matched by file name
+var variantOf = map[IllustrationType]IllustrationType{
+	BodyOutline:    Body,
+	HairBack:       Hair,
+	HairOutline:    Hair,
+	MouthOutline:   Mouth,
+	HatOutline:     Hat,
+	OverlayOutline: Overlay,
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Asset - asset described by the manifest entry, without its SVG contents
+func (e ManifestEntry) Asset(iType IllustrationType) Asset {
+	return Asset{
+		FileName:      e.File,
+		Type:          iType,
+		ManifestID:    e.ID,
+		HairColored:   contains(e.Tint, TintHair),
+		BodyColored:   contains(e.Tint, TintBody),
+		Sex:           e.Sex,
+		LightOnly:     e.LightOnly,
+		DarkColored:   contains(e.DarkMode, DarkBlack),
+		DarkBWColored: contains(e.DarkMode, DarkWhite),
+		BLK299:        e.BLK299,
+		BadgeType:     e.Badge,
+		BadgeBodies:   e.BadgeBodies,
+		Weight:        e.Weight,
+		Since:         e.Since,
+	}
+}
+
+// ReadManifest - read manifest of an illustration directory
+func ReadManifest(fsys fs.FS, dir string) (Manifest, error) {
+	var m Manifest
+	data, err := fs.ReadFile(fsys, path.Join(dir, ManifestFile))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %s", path.Join(dir, ManifestFile), err)
+	}
+	return m, nil
+}
+
+// LoadManifestAssets - load assets of an illustration type with their manifest, in manifest order
+func LoadManifestAssets(fsys fs.FS, iType IllustrationType) ([]Asset, error) {
+	m, err := ReadManifest(fsys, string(iType))
+	if err != nil {
+		return nil, err
+	}
+	var assets []Asset
+	for _, e := range m.Assets {
+		a := e.Asset(iType)
+		a.IllustrationPath = path.Join(string(iType), e.File)
+		if a.SVGContents, err = fs.ReadFile(fsys, a.IllustrationPath); err != nil {
+			return nil, err
+		}
+		assets = append(assets, a)
+	}
+	return assets, nil
+}
+
+// ValidateManifests - check the manifests of every illustration type against each other and their SVGs
+func ValidateManifests(fsys fs.FS) error {
+	manifests := map[IllustrationType]Manifest{}
+	for _, iType := range illustrationTypes {
+		m, err := ReadManifest(fsys, string(iType))
+		if err != nil {
+			return err
+		}
+		manifests[iType] = m
+	}
+	var problems []string
+	for _, iType := range illustrationTypes {
+		problems = append(problems, validateManifest(fsys, iType, manifests)...)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("Invalid asset manifests:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// validateManifest - problems of the manifest of an illustration type
+func validateManifest(fsys fs.FS, iType IllustrationType, manifests map[IllustrationType]Manifest) []string {
+	var problems []string
+	report := func(file string, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s/%s: %s", iType, file, fmt.Sprintf(format, args...)))
+	}
+	dir := string(iType)
+
+	// Every SVG needs exactly one entry
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	entries := map[string]ManifestEntry{}
+	ids := map[int]string{}
+	for _, e := range manifests[iType].Assets {
+		if _, ok := entries[e.File]; ok {
+			report(e.File, "listed more than once")
+		}
+		entries[e.File] = e
+		if iType != Badge {
+			if e.ID <= 0 {
+				report(e.File, "needs a positive id")
+			} else if other, ok := ids[e.ID]; ok {
+				report(e.File, "has the same id as %s", other)
+			}
+			ids[e.ID] = e.File
+		}
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".svg") {
+			if _, ok := entries[f.Name()]; !ok {
+				report(f.Name(), "missing from %s", ManifestFile)
+			}
+		}
+	}
+
+	for _, e := range manifests[iType].Assets {
+		if e.Sex != Male && e.Sex != Female && e.Sex != Neutral {
+			report(e.File, "sex must be '%s', '%s' or '%s'", Male, Female, Neutral)
+		}
+		for _, tint := range e.Tint {
+			if tint != TintBody && tint != TintHair {
+				report(e.File, "tint must be '%s' or '%s'", TintBody, TintHair)
+			}
+		}
+		for _, dark := range e.DarkMode {
+			if dark != DarkBlack && dark != DarkWhite {
+				report(e.File, "dark_mode must be '%s' or '%s'", DarkBlack, DarkWhite)
+			}
+		}
+		if e.LightOnly && len(e.DarkMode) > 0 {
+			report(e.File, "light_only assets are never drawn on dark bodies")
+		}
+		if e.Weight < 0 {
+			report(e.File, "weight can't be negative")
+		}
+		if e.Since < 0 || e.Since > LatestVersion {
+			report(e.File, "since must be a version between %d and %d", V1, LatestVersion)
+		}
+
+		// Variants share the file name and id of their base asset
+		if base, ok := variantOf[iType]; ok {
+			found := false
+			for _, b := range manifests[base].Assets {
+				if b.File == e.File {
+					found = true
+					if b.ID != e.ID {
+						report(e.File, "id %d doesn't match %s id %d", e.ID, base, b.ID)
+					}
+				}
+			}
+			if !found {
+				report(e.File, "has no %s asset", base)
+			}
+		}
+
+		if iType == Badge {
+			switch e.Badge {
+			case spc.BTDonor, spc.BTExchange, spc.BTNode, spc.BTService:
+			default:
+				report(e.File, "badge must be '%s', '%s', '%s' or '%s'", spc.BTDonor, spc.BTExchange, spc.BTNode, spc.BTService)
+			}
+			if len(e.BadgeBodies) == 0 {
+				report(e.File, "badge_bodies is empty")
+			}
+		} else if e.Badge != "" || len(e.BadgeBodies) > 0 {
+			report(e.File, "only badges have badge and badge_bodies")
+		}
+
+		// Slots of the SVG must match the metadata
+		contents, err := fs.ReadFile(fsys, path.Join(dir, e.File))
+		if errors.Is(err, fs.ErrNotExist) {
+			report(e.File, "listed in %s but doesn't exist", ManifestFile)
+			continue
+		} else if err != nil {
+			report(e.File, "%s", err)
+			continue
+		}
+		template, err := compileTemplate(contents)
+		if err != nil {
+			report(e.File, "%s", err)
+			continue
+		}
+		if template.has(slotBodyColor) && !contains(e.Tint, TintBody) {
+			report(e.File, "has a body color slot but isn't tinted with body")
+		}
+		if (template.has(slotHairColor) || template.has(slotMouthColor)) && !contains(e.Tint, TintHair) {
+			report(e.File, "has a hair color slot but isn't tinted with hair")
+		}
+		if e.BLK299 && !template.has(slotBlk299Opacity) {
+			report(e.File, "is blk299 but has no blk299 opacity slot")
+		}
+	}
+
+	// Every body gets exactly one badge of every type
+	if iType == Badge {
+		placed := map[spc.BadgeType]map[int]string{}
+		for _, e := range manifests[Badge].Assets {
+			if placed[e.Badge] == nil {
+				placed[e.Badge] = map[int]string{}
+			}
+			for _, id := range e.BadgeBodies {
+				if other, ok := placed[e.Badge][id]; ok {
+					report(e.File, "places a badge on body %d like %s", id, other)
+				}
+				placed[e.Badge][id] = e.File
+			}
+		}
+		var badgeTypes []string
+		for badgeType := range placed {
+			badgeTypes = append(badgeTypes, string(badgeType))
+		}
+		sort.Strings(badgeTypes)
+		for _, badgeType := range badgeTypes {
+			for _, body := range manifests[Body].Assets {
+				if _, ok := placed[spc.BadgeType(badgeType)][body.ID]; !ok {
+					report(badgeType, "no badge for body %d", body.ID)
+				}
+			}
+		}
+	}
+	return problems
+}
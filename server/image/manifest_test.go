@@ -0,0 +1,165 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/appditto/natricon/server/assets"
+	"github.com/appditto/natricon/server/spc"
+)
+
+const illustrationsDir = "../assets/illustrations"
+
+func TestValidateManifests(t *testing.T) {
+	if err := ValidateManifests(os.DirFS(illustrationsDir)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadAssetsFromDirectory(t *testing.T) {
+	sm, err := loadAssets(os.DirFS(illustrationsDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded := GetAssets().typedAssets()
+	for iType, lists := range sm.typedAssets() {
+		for i, list := range lists {
+			if len(list) != len(embedded[iType][i]) {
+				t.Fatalf("%s: expected %d assets but got %d", iType, len(embedded[iType][i]), len(list))
+			}
+			for j := range list {
+				if list[j].FileName != embedded[iType][i][j].FileName || !bytes.Equal(list[j].SVGContents, embedded[iType][i][j].SVGContents) {
+					t.Errorf("%s: expected %s at %d but got %s", iType, embedded[iType][i][j].FileName, j, list[j].FileName)
+				}
+			}
+		}
+	}
+	// Assets are picked in manifest order, which existing natricons depend on
+	if first := GetAssets().bodyAssets[0].FileName; first != "10_sq-84.svg" {
+		t.Errorf("Expected first body to be 10_sq-84.svg but got %s", first)
+	}
+}
+
+func TestGetBadgeAssetByBody(t *testing.T) {
+	for _, test := range []struct {
+		body     int
+		v        Version
+		expected string
+	}{
+		{1, V2, "donor_b1_b2_b3_b4_b5_b6_b7_b8_b9_b10.svg"},
+		{2, V2, "donor_b1_b2_b3_b4_b5_b6_b7_b8_b9_b10.svg"},
+		{21, V2, "donor_b11_b12_b13_b14_b15_b16_b17_b18_b19_b20_b21.svg"},
+		{32, V2, "donor_b30_b31_b32.svg"},
+		// Version 1 finds b1 and b2 in the file name of the badge of bodies 11 to 21 first
+		{1, V1, "donor_b11_b12_b13_b14_b15_b16_b17_b18_b19_b20_b21.svg"},
+		{2, V1, "donor_b11_b12_b13_b14_b15_b16_b17_b18_b19_b20_b21.svg"},
+		{3, V1, "donor_b1_b2_b3_b4_b5_b6_b7_b8_b9_b10.svg"},
+		{32, V1, "donor_b30_b31_b32.svg"},
+	} {
+		badge := GetBadgeAsset(GetBodyAssetWithID(test.body), spc.BTDonor, test.v)
+		if badge == nil || badge.FileName != test.expected {
+			t.Errorf("Expected body %d to get badge %s in version %d but got %v", test.body, test.expected, test.v, badge)
+		}
+	}
+}
+
+func TestValidateManifestsInconsistent(t *testing.T) {
+	for _, test := range []struct {
+		iType    IllustrationType
+		from, to string
+		expected string
+	}{
+		{Body, `"id": 1,`, `"id": 2,`, "body/2_sq-52.svg: has the same id as 1_sq-48.svg"},
+		{Body, `, "tint": ["body"]}`, `}`, "isn't tinted with body"},
+		{Mouth, `"sex": "M"`, `"sex": "X"`, "sex must be"},
+		{Eye, `"dark_mode": ["black", "white"]`, `"dark_mode": ["grey"]`, "dark_mode must be"},
+		{HairOutline, `"id": 1,`, `"id": 99,`, "doesn't match hair-front id 1"},
+		{Badge, `"badge_bodies": [30, 31, 32]`, `"badge_bodies": [30, 31]`, "no badge for body 32"},
+	} {
+		fsys := copyIllustrations(t)
+		file := path.Join(string(test.iType), ManifestFile)
+		data := string(fsys[file].Data)
+		if !strings.Contains(data, test.from) {
+			t.Fatalf("%s has no %s", file, test.from)
+		}
+		fsys[file] = &fstest.MapFile{Data: []byte(strings.Replace(data, test.from, test.to, 1))}
+		err := ValidateManifests(fsys)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected error containing %q but got %v", test.expected, err)
+		}
+	}
+}
+
+// copyIllustrations - in-memory copy of the built-in illustrations
+func copyIllustrations(t *testing.T) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	err := fs.WalkDir(assets.Illustrations, "illustrations", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(assets.Illustrations, name)
+		fsys[strings.TrimPrefix(name, "illustrations/")] = &fstest.MapFile{Data: data}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fsys
+}
+
+// Values the built-in illustrations were drawn with where natricons fill in their colors
+var illustrationPlaceholders = []struct {
+	attrs []string
+	value string
+}{
+	{[]string{"fill", "stroke"}, "#00FFFF"},
+	{[]string{"fill", "stroke"}, "#FF0000"},
+	{[]string{"fill", "stroke"}, "#FFFF00"},
+	{[]string{"fill-opacity"}, "0.15"},
+	{[]string{"fill-opacity"}, "0.299"},
+	{[]string{"fill", "stroke"}, "black"},
+	{[]string{"fill", "stroke"}, "white"},
+	{[]string{"stroke-width"}, "16"},
+}
+
+// Every placeholder value of the built-in illustrations must be bound to a slot with a class
+func TestIllustrationsDeclareSlots(t *testing.T) {
+	err := fs.WalkDir(assets.Illustrations, "illustrations", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || path.Ext(name) != ".svg" {
+			return err
+		}
+		contents, err := fs.ReadFile(assets.Illustrations, name)
+		if err != nil {
+			return err
+		}
+		template, err := compileTemplate(contents)
+		if err != nil {
+			t.Errorf("Failed to compile %s %s", name, err)
+			return nil
+		}
+		for _, l := range illustrationPlaceholders {
+			for _, attr := range l.attrs {
+				placeholder := fmt.Sprintf("%s=\"%s\"", attr, l.value)
+				slotted := 0
+				for _, seg := range template.segments {
+					if seg.slot != nSlots && seg.original == placeholder {
+						slotted++
+					}
+				}
+				if n := bytes.Count(contents, []byte(" "+placeholder)); n != slotted {
+					t.Errorf("Expected every %s of %s to be bound to a slot but %d of %d are", placeholder, name, slotted, n)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
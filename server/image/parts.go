@@ -0,0 +1,91 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Part - group of layers that can be rendered on its own
+type Part string
+
+const (
+	PartOutline   Part = "outline"
+	PartBackHair  Part = "back_hair"
+	PartBody      Part = "body"
+	PartHair      Part = "hair"
+	PartAccessory Part = "accessory"
+	PartMouth     Part = "mouth"
+	PartEye       Part = "eye"
+	PartBadge     Part = "badge"
+)
+
+// Parts - every part from bottom to top
+var Parts = []Part{PartOutline, PartBackHair, PartBody, PartHair, PartMouth, PartEye, PartAccessory, PartBadge}
+
+// ParseParts - parse comma separated list of parts to render
+// Parts prefixed with '-' are excluded from all parts instead, like '-badge,-outline'
+// Returns nil for an empty list, which renders every part
+func ParseParts(list string) ([]Part, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	selected := map[Part]bool{}
+	exclude := false
+	for i, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		excluded := strings.HasPrefix(name, "-")
+		if i == 0 {
+			exclude = excluded
+		} else if excluded != exclude {
+			return nil, errors.New("Parts can't be both included and excluded")
+		}
+		part, ok := parsePart(strings.TrimPrefix(name, "-"))
+		if !ok {
+			return nil, fmt.Errorf("Unknown part %s", name)
+		}
+		selected[part] = true
+	}
+	ret := []Part{}
+	for _, p := range Parts {
+		if selected[p] != exclude {
+			ret = append(ret, p)
+		}
+	}
+	return ret, nil
+}
+
+func parsePart(name string) (Part, bool) {
+	for _, p := range Parts {
+		if string(p) == name {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// layerPart - get part a layer belongs to by its group id
+func layerPart(id string) Part {
+	id = strings.TrimSuffix(id, "Alt")
+	if strings.HasSuffix(id, "Outline") {
+		return PartOutline
+	} else if id == "backhair" {
+		return PartBackHair
+	} else if id == "hat" || id == "glasses" || strings.HasPrefix(id, "overlay") {
+		return PartAccessory
+	}
+	return Part(id)
+}
+
+// HasPart - whether the part is rendered, all parts are rendered when Parts is nil
+func (a Accessories) HasPart(part Part) bool {
+	if a.Parts == nil {
+		return true
+	}
+	for _, p := range a.Parts {
+		if p == part {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package image
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/appditto/natricon/server/color"
+	"github.com/appditto/natricon/server/spc"
+)
+
+func TestParseParts(t *testing.T) {
+	if parts, err := ParseParts(""); err != nil || parts != nil {
+		t.Errorf("Expected every part for empty list but got %v %v", parts, err)
+	}
+	if parts, _ := ParseParts("hair, body"); !reflect.DeepEqual(parts, []Part{PartBody, PartHair}) {
+		t.Errorf("Unexpected included parts %v", parts)
+	}
+	if parts, _ := ParseParts("-badge,-outline"); !reflect.DeepEqual(parts, []Part{PartBackHair, PartBody, PartHair, PartMouth, PartEye, PartAccessory}) {
+		t.Errorf("Unexpected excluded parts %v", parts)
+	}
+	for _, invalid := range []string{"body,-badge", "nose"} {
+		if _, err := ParseParts(invalid); err == nil {
+			t.Errorf("Expected error for %s", invalid)
+		}
+	}
+}
+
+func TestCombineSVGParts(t *testing.T) {
+	accessories, err := GetAccessoriesForHash(benchHash, spc.BTDonor, true, &color.RGB{R: 255, G: 255, B: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessories.Parts, _ = ParseParts("-badge,-outline")
+	svg, err := CombineSVG(accessories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"badge", "bodyOutline", "hairOutline"} {
+		if bytes.Contains(svg, []byte("id=\""+id+"\"")) {
+			t.Errorf("Expected %s to be left out", id)
+		}
+	}
+	for _, id := range []string{"body", "hair", "mouth", "eye"} {
+		if !bytes.Contains(svg, []byte("id=\""+id+"\"")) {
+			t.Errorf("Expected %s to be rendered", id)
+		}
+	}
+}
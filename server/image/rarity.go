@@ -0,0 +1,188 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/appditto/natricon/server/color"
+	"github.com/appditto/natricon/server/spc"
+)
+
+// RaritySampleSize - number of natricons rarity tables are sampled from
+// Hashes of addresses are uniformly random, so a fixed sample of hashes has the distribution of every natricon
+const RaritySampleSize = 50000
+
+// Traits rarity is scored on, in the order they are reported
+var rarityTraits = []string{"body", "hair", "mouth", "eye", "body_color", "hair_color"}
+
+// Names of the 30 degree hue sectors of color buckets, starting at red
+var hueNames = []string{"red", "orange", "yellow", "chartreuse", "green", "spring", "cyan", "azure", "blue", "violet", "magenta", "rose"}
+
+// Colors below this HSB saturation are gray whatever their hue
+const graySaturation = 0.1
+
+// Perceived brightness from which colors are light, colors below LightToDarkSwitchPoint are dark
+const lightBrightness = 60
+
+// colorBucket - hue and tone of a color, like azure/light
+func colorBucket(c color.RGB) string {
+	hsb := c.ToHSB()
+	hue := "gray"
+	if hsb.S >= graySaturation {
+		hue = hueNames[int(math.Mod(hsb.H+15, 360)/30)]
+	}
+	tone := "medium"
+	if brightness := c.PerceivedBrightness(); brightness < LightToDarkSwitchPoint {
+		tone = "dark"
+	} else if brightness >= lightBrightness {
+		tone = "light"
+	}
+	return hue + "/" + tone
+}
+
+// rarityValues - value of every rarity trait of a natricon, in rarityTraits order
+func rarityValues(accessories Accessories) []string {
+	return []string{
+		strconv.Itoa(accessories.BodyAsset.ID()),
+		strconv.Itoa(accessories.HairAsset.ID()),
+		strconv.Itoa(accessories.MouthAsset.ID()),
+		strconv.Itoa(accessories.EyeAsset.ID()),
+		colorBucket(accessories.BodyColor),
+		colorBucket(accessories.HairColor),
+	}
+}
+
+// RarityTable - how often every asset and color bucket occurs among natricons of a version
+type RarityTable struct {
+	Version Version
+	Size    int
+	Counts  map[string]map[string]int // Trait to value to the number of sampled natricons with it
+	bits    []float64                 // Overall bits of every sampled natricon, sorted
+}
+
+// TraitRarity - how rare a trait of a natricon is
+type TraitRarity struct {
+	Trait     string  `json:"trait"`
+	Value     string  `json:"value"`     // Asset id, or color bucket like azure/light
+	Count     int     `json:"count"`     // Sampled natricons with the value
+	Frequency float64 `json:"frequency"` // Share of natricons with the value
+	OneIn     int     `json:"one_in"`    // One in how many natricons have the value, 0 when none of the sample has it
+	Bits      float64 `json:"bits"`      // -log2 of the frequency, higher is rarer
+}
+
+// Rarity - how rare a natricon is
+type Rarity struct {
+	Traits     []TraitRarity `json:"traits"`
+	Bits       float64       `json:"bits"`        // Sum of the bits of every trait
+	Percentile float64       `json:"percentile"`  // Share of natricons in percent with fewer bits, that are more common
+	SampleSize int           `json:"sample_size"` // Number of natricons the rarity tables were sampled from
+}
+
+// sampleHash - hash of the sampled natricon i
+func sampleHash(i int) string {
+	digest := sha256.Sum256([]byte(fmt.Sprintf("natricon/rarity/%d", i)))
+	return hex.EncodeToString(digest[:])
+}
+
+// NewRarityTable - sample rarity table of a version from size natricons, spread over every CPU
+func NewRarityTable(v Version, size int) (*RarityTable, error) {
+	values := make([][]string, size)
+	errs := make(chan error, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < size; i += runtime.NumCPU() {
+				accessories, err := GetAccessoriesForHashVersion(sampleHash(i), v, spc.BTNone, false, nil)
+				if err != nil {
+					errs <- err
+					return
+				}
+				values[i] = rarityValues(accessories)
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	t := &RarityTable{Version: v, Size: size, Counts: map[string]map[string]int{}}
+	for _, trait := range rarityTraits {
+		t.Counts[trait] = map[string]int{}
+	}
+	for _, sample := range values {
+		for i, value := range sample {
+			t.Counts[rarityTraits[i]][value]++
+		}
+	}
+	t.bits = make([]float64, size)
+	for i, sample := range values {
+		t.bits[i] = t.rarity(sample).Bits
+	}
+	sort.Float64s(t.bits)
+	return t, nil
+}
+
+// rarity - rarity of trait values without the percentile
+func (t *RarityTable) rarity(values []string) Rarity {
+	r := Rarity{SampleSize: t.Size}
+	for i, value := range values {
+		trait := TraitRarity{Trait: rarityTraits[i], Value: value, Count: t.Counts[rarityTraits[i]][value]}
+		trait.Frequency = float64(trait.Count) / float64(t.Size)
+		if trait.Count > 0 {
+			trait.OneIn = int(math.Round(1 / trait.Frequency))
+		}
+		// Values none of the sample has count as rarer than the rarest sampled ones
+		trait.Bits = -math.Log2(math.Max(float64(trait.Count), 0.5) / float64(t.Size))
+		r.Bits += trait.Bits
+		r.Traits = append(r.Traits, trait)
+	}
+	return r
+}
+
+// Rarity - how rare a natricon is compared to the sampled ones
+func (t *RarityTable) Rarity(accessories Accessories) Rarity {
+	r := t.rarity(rarityValues(accessories))
+	r.Percentile = math.Round(float64(sort.SearchFloat64s(t.bits, r.Bits))/float64(t.Size)*1000) / 10
+	r.Bits = math.Round(r.Bits*100) / 100
+	for i := range r.Traits {
+		r.Traits[i].Bits = math.Round(r.Traits[i].Bits*100) / 100
+	}
+	return r
+}
+
+// rarityTable - rarity table of a version, sampled the first time it's needed
+type rarityTable struct {
+	once  sync.Once
+	table *RarityTable
+	err   error
+}
+
+var rarityTables = map[Version]*rarityTable{}
+
+func init() {
+	for _, v := range Versions {
+		rarityTables[v] = &rarityTable{}
+	}
+}
+
+// GetRarityTable - get rarity table of a version, sampling it takes a few seconds the first time
+func GetRarityTable(v Version) (*RarityTable, error) {
+	rt, ok := rarityTables[v]
+	if !ok {
+		return nil, fmt.Errorf("Invalid version")
+	}
+	rt.once.Do(func() {
+		rt.table, rt.err = NewRarityTable(v, RaritySampleSize)
+	})
+	return rt.table, rt.err
+}
@@ -0,0 +1,64 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/appditto/natricon/server/color"
+	"github.com/appditto/natricon/server/spc"
+)
+
+func TestColorBucket(t *testing.T) {
+	for hex, expected := range map[string]string{
+		"#ff0000": "red/medium",
+		"#0000ff": "blue/dark",
+		"#ffff00": "yellow/light",
+		"#00ffff": "cyan/light",
+		"#808080": "gray/medium",
+		"#fb0a14": "red/medium",
+		"#ff0080": "rose/medium",
+	} {
+		if bucket := colorBucket(*color.HTMLToRGBAlt(hex)); bucket != expected {
+			t.Errorf("Expected %s for %s but got %s", expected, hex, bucket)
+		}
+	}
+}
+
+func TestRarityTable(t *testing.T) {
+	table, err := NewRarityTable(V1, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, trait := range rarityTraits {
+		total := 0
+		for _, count := range table.Counts[trait] {
+			total += count
+		}
+		if total != table.Size {
+			t.Errorf("Expected %d natricons to have a %s but got %d", table.Size, trait, total)
+		}
+	}
+
+	// Sampled natricons have every trait in the table
+	accessories, _ := GetAccessoriesForHashVersion(sampleHash(0), V1, spc.BTNone, false, nil)
+	r := table.Rarity(accessories)
+	if len(r.Traits) != len(rarityTraits) || r.Percentile < 0 || r.Percentile >= 100 {
+		t.Errorf("Unexpected rarity %v", r)
+	}
+	for _, trait := range r.Traits {
+		if trait.Count == 0 || trait.OneIn < 1 {
+			t.Errorf("Expected %s of a sampled natricon to be in the table but got %v", trait.Trait, trait)
+		}
+	}
+
+	// Values none of the sample has are the rarest
+	accessories.BodyAsset = Asset{ManifestID: 999}
+	if unseen := table.Rarity(accessories); unseen.Traits[0].Count != 0 || unseen.Traits[0].OneIn != 0 || unseen.Bits <= r.Bits {
+		t.Errorf("Expected an unsampled body to be rarer but got %v", unseen.Traits[0])
+	}
+
+	// Tables are the same wherever they're sampled
+	again, _ := NewRarityTable(V1, 2000)
+	if again.Rarity(accessories).Bits != table.Rarity(accessories).Bits {
+		t.Errorf("Expected rarity to be deterministic")
+	}
+}
@@ -0,0 +1,192 @@
+package image
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Date layouts of seasons, recurring every year or happening once
+const recurringDateLayout = "01-02"
+const onceDateLayout = "2006-01-02"
+
+// Season - overlays drawn on every natricon between two dates, in UTC
+type Season struct {
+	Name     string   `json:"name"`
+	Start    string   `json:"start"`    // MM-DD to recur every year, or YYYY-MM-DD
+	End      string   `json:"end"`      // Inclusive, in the same layout as Start
+	Overlays []string `json:"overlays"` // Names of overlay assets, from bottom to top
+	start    time.Time
+	end      time.Time
+	once     bool
+}
+
+// seasonsConfig - file seasons are loaded from
+type seasonsConfig struct {
+	Seasons []Season `json:"seasons"`
+}
+
+// Seasons loaded with LoadSeasons, earlier seasons win when they overlap
+var seasons []Season
+
+// Config seasons were loaded from, part of the content digest
+var seasonsData []byte
+
+// LoadSeasons - load seasonal overlays from a JSON config file
+// Must be called before natricons are generated
+func LoadSeasons(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	parsed, err := parseSeasons(data)
+	if err != nil {
+		return fmt.Errorf("Invalid seasons config %s: %s", path, err)
+	}
+	seasons = parsed
+	seasonsData = data
+	return nil
+}
+
+// parseSeasons - parse and validate seasons config
+func parseSeasons(data []byte) ([]Season, error) {
+	var config seasonsConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for i := range config.Seasons {
+		s := &config.Seasons[i]
+		if s.Name == "" || names[s.Name] {
+			return nil, errors.New("Seasons need a unique name")
+		}
+		names[s.Name] = true
+		layout := recurringDateLayout
+		s.once = len(s.Start) == len(onceDateLayout)
+		if s.once {
+			layout = onceDateLayout
+		}
+		var err error
+		if s.start, err = time.Parse(layout, s.Start); err != nil {
+			return nil, fmt.Errorf("Season %s has invalid start %s", s.Name, s.Start)
+		}
+		if s.end, err = time.Parse(layout, s.End); err != nil {
+			return nil, fmt.Errorf("Season %s has invalid end %s, it must be in the same layout as start", s.Name, s.End)
+		}
+		if s.once && s.end.Before(s.start) {
+			return nil, fmt.Errorf("Season %s ends before it starts", s.Name)
+		}
+		if len(s.Overlays) == 0 {
+			return nil, fmt.Errorf("Season %s has no overlays", s.Name)
+		}
+		for _, overlay := range s.Overlays {
+			if findOverlay(overlay) == nil {
+				return nil, fmt.Errorf("Season %s has unknown overlay %s", s.Name, overlay)
+			}
+		}
+	}
+	return config.Seasons, nil
+}
+
+// activeOn - whether the season is active at a time
+// Recurring seasons can wrap around the new year, like 12-20 to 01-05
+func (s Season) activeOn(t time.Time) bool {
+	t = t.UTC()
+	if s.once {
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return !day.Before(s.start) && !day.After(s.end)
+	}
+	monthDay := func(m time.Month, d int) int { return int(m)*100 + d }
+	day, start, end := monthDay(t.Month(), t.Day()), monthDay(s.start.Month(), s.start.Day()), monthDay(s.end.Month(), s.end.Day())
+	if start <= end {
+		return day >= start && day <= end
+	}
+	return day >= start || day <= end
+}
+
+// ActiveSeason - season active at a time, nil when there is none
+func ActiveSeason(t time.Time) *Season {
+	for i := range seasons {
+		if seasons[i].activeOn(t) {
+			return &seasons[i]
+		}
+	}
+	return nil
+}
+
+// NextSeasonChange - first midnight UTC after a time at which another season or none is active, seasons only change at midnight
+// ok is false when the active season stays the same for a year
+func NextSeasonChange(t time.Time) (time.Time, bool) {
+	if len(seasons) == 0 {
+		return time.Time{}, false
+	}
+	name := func(s *Season) string {
+		if s == nil {
+			return ""
+		}
+		return s.Name
+	}
+	active := name(ActiveSeason(t))
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	for days := 1; days <= 366; days++ {
+		day := midnight.AddDate(0, 0, days)
+		if name(ActiveSeason(day)) != active {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// GetSeason - get season by name, nil when there is none
+func GetSeason(name string) *Season {
+	for i := range seasons {
+		if seasons[i].Name == name {
+			return &seasons[i]
+		}
+	}
+	return nil
+}
+
+// findOverlay - get overlay asset by name
+func findOverlay(name string) *Asset {
+	assets := GetAssets().overlayAssets
+	for i := range assets {
+		if assets[i].AccessoryName() == name {
+			return &assets[i]
+		}
+	}
+	return nil
+}
+
+// getOverlayOutlineAsset - return outline illustration for a given overlay asset, nil for overlays without one
+func getOverlayOutlineAsset(overlayAsset Asset) *Asset {
+	for _, oa := range GetAssets().overlayOutlineAssets {
+		if oa.FileName == overlayAsset.FileName {
+			return &oa
+		}
+	}
+	return nil
+}
+
+// WearSeason - draw the overlays of a season, with outlines when the natricon is outlined
+func (a *Accessories) WearSeason(season *Season) {
+	a.OverlayAssets, a.OverlayOutlineAssets = nil, nil
+	if season == nil {
+		return
+	}
+	for _, name := range season.Overlays {
+		overlay := findOverlay(name)
+		if overlay == nil {
+			continue
+		}
+		a.OverlayAssets = append(a.OverlayAssets, overlay)
+		if a.BodyOutlineAsset != nil {
+			if outline := getOverlayOutlineAsset(*overlay); outline != nil {
+				a.OverlayOutlineAssets = append(a.OverlayOutlineAssets, outline)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package image
+
+import (
+	"testing"
+	"time"
+
+	"github.com/appditto/natricon/server/color"
+	"github.com/appditto/natricon/server/spc"
+)
+
+func TestSeasonActive(t *testing.T) {
+	parsed, err := parseSeasons([]byte(`{"seasons": [
+		{"name": "holidays", "start": "12-20", "end": "01-05", "overlays": ["santa-hat"]},
+		{"name": "launch", "start": "2026-06-24", "end": "2026-06-26", "overlays": ["confetti"]},
+		{"name": "summer", "start": "06-01", "end": "08-31", "overlays": ["confetti"]}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse seasons %s", err)
+	}
+	seasons = parsed
+	defer func() { seasons = nil }()
+
+	for date, expected := range map[string]string{
+		"2026-12-19T23:59:59Z":      "",
+		"2026-12-20T00:00:00Z":      "holidays",
+		"2027-01-05T12:00:00Z":      "holidays",
+		"2027-01-06T00:00:00Z":      "",
+		"2026-06-25T08:00:00Z":      "launch",
+		"2027-06-25T08:00:00Z":      "summer",
+		"2026-06-26T23:30:00-02:00": "summer",
+	} {
+		at, _ := time.Parse(time.RFC3339, date)
+		name := ""
+		if season := ActiveSeason(at); season != nil {
+			name = season.Name
+		}
+		if name != expected {
+			t.Errorf("Expected season '%s' on %s but got '%s'", expected, date, name)
+		}
+	}
+}
+
+func TestNextSeasonChange(t *testing.T) {
+	if _, ok := NextSeasonChange(time.Now()); ok {
+		t.Error("Expected no season change without seasons")
+	}
+	parsed, err := parseSeasons([]byte(`{"seasons": [
+		{"name": "holidays", "start": "12-20", "end": "01-05", "overlays": ["santa-hat"]}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse seasons %s", err)
+	}
+	seasons = parsed
+	defer func() { seasons = nil }()
+
+	for date, expected := range map[string]string{
+		"2026-12-19T23:30:00Z":      "2026-12-20T00:00:00Z",
+		"2026-11-01T12:00:00Z":      "2026-12-20T00:00:00Z",
+		"2026-12-20T00:00:00Z":      "2027-01-06T00:00:00Z",
+		"2027-01-05T22:00:00-05:00": "2027-12-20T00:00:00Z",
+	} {
+		at, _ := time.Parse(time.RFC3339, date)
+		change, ok := NextSeasonChange(at)
+		if !ok || change.Format(time.RFC3339) != expected {
+			t.Errorf("Expected season to change at %s after %s but got %s", expected, date, change.Format(time.RFC3339))
+		}
+	}
+}
+
+func TestParseSeasonsInvalid(t *testing.T) {
+	for _, config := range []string{
+		`{"seasons": [{"name": "a", "start": "13-01", "end": "12-31", "overlays": ["confetti"]}]}`,
+		`{"seasons": [{"name": "a", "start": "2026-01-01", "end": "12-31", "overlays": ["confetti"]}]}`,
+		`{"seasons": [{"name": "a", "start": "2026-02-01", "end": "2026-01-01", "overlays": ["confetti"]}]}`,
+		`{"seasons": [{"name": "a", "start": "01-01", "end": "01-02", "overlays": ["fireworks"]}]}`,
+		`{"seasons": [{"name": "a", "start": "01-01", "end": "01-02"}]}`,
+		`{"seasons": [{"name": "a", "start": "01-01", "end": "01-02", "overlays": ["confetti"]}, {"name": "a", "start": "01-01", "end": "01-02", "overlays": ["confetti"]}]}`,
+	} {
+		if _, err := parseSeasons([]byte(config)); err == nil {
+			t.Errorf("Expected error for %s", config)
+		}
+	}
+}
+
+func TestWearSeason(t *testing.T) {
+	season := &Season{Name: "holidays", Overlays: []string{"confetti", "santa-hat"}}
+	accessories, _ := GetAccessoriesForHash(benchHash, spc.BTNone, true, &color.RGB{R: 0, G: 0, B: 0})
+	accessories.WearSeason(season)
+	if len(accessories.OverlayAssets) != 2 || accessories.OverlayAssets[1].AccessoryName() != "santa-hat" {
+		t.Errorf("Expected overlays in order but got %v", accessories.OverlayAssets)
+	}
+	// Confetti has no outline
+	if len(accessories.OverlayOutlineAssets) != 1 {
+		t.Errorf("Expected one overlay outline but got %d", len(accessories.OverlayOutlineAssets))
+	}
+	layers := buildLayers(accessories)
+	for i, l := range layers {
+		if l.id == "overlay0" && layers[i-1].id != "hair" {
+			t.Errorf("Expected overlays after hair but got %s", layers[i-1].id)
+		}
+	}
+	accessories.WearSeason(nil)
+	if accessories.OverlayAssets != nil || accessories.OverlayOutlineAssets != nil {
+		t.Error("Expected no overlays without a season")
+	}
+}
@@ -0,0 +1,69 @@
+package image
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/appditto/natricon/server/color"
+)
+
+// Shape - outline of the canvas natricons are clipped to
+type Shape string
+
+const (
+	Square  Shape = "square"
+	Circle  Shape = "circle"
+	Rounded Shape = "rounded"
+)
+
+// Shapes - every supported shape
+var Shapes = []Shape{Square, Circle, Rounded}
+
+const roundedRadius = 96         // Corner radius of rounded natricons
+const shapeClipID = "shape"      // ID of the clip path natricons are clipped with
+const autoBackgroundTint = 0.75  // How much the body color is mixed with white for auto backgrounds of dark bodies
+const autoBackgroundShade = 0.35 // How much the body color is kept when darkening auto backgrounds of light bodies
+
+// ParseShape - get shape by name, ok is false if the shape doesn't exist
+func ParseShape(name string) (Shape, bool) {
+	for _, s := range Shapes {
+		if string(s) == strings.ToLower(name) {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// AutoBackground - background color derived from the body color
+// Dark bodies get a pastel tint of their color, light bodies a deep shade of it
+func AutoBackground(bodyColor color.RGB) color.RGB {
+	if LightToDarkSwitchPoint > int(bodyColor.PerceivedBrightness()) {
+		return color.RGB{
+			R: bodyColor.R + (255-bodyColor.R)*autoBackgroundTint,
+			G: bodyColor.G + (255-bodyColor.G)*autoBackgroundTint,
+			B: bodyColor.B + (255-bodyColor.B)*autoBackgroundTint,
+		}
+	}
+	return color.RGB{
+		R: bodyColor.R * autoBackgroundShade,
+		G: bodyColor.G * autoBackgroundShade,
+		B: bodyColor.B * autoBackgroundShade,
+	}
+}
+
+// shapeElement - SVG element covering the view box in the given shape
+func shapeElement(shape Shape, vb box, attrs string) string {
+	scale := vb.width() / DefaultSize
+	switch shape {
+	case Circle:
+		return fmt.Sprintf("<circle cx=\"%s\" cy=\"%s\" r=\"%s\" %s/>", formatNumber(vb.MinX+vb.width()/2), formatNumber(vb.MinY+vb.height()/2), formatNumber(vb.width()/2), attrs)
+	case Rounded:
+		return fmt.Sprintf("<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" rx=\"%s\" %s/>", formatNumber(vb.MinX), formatNumber(vb.MinY), formatNumber(vb.width()), formatNumber(vb.height()), formatNumber(roundedRadius*scale), attrs)
+	}
+	return fmt.Sprintf("<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" %s/>", formatNumber(vb.MinX), formatNumber(vb.MinY), formatNumber(vb.width()), formatNumber(vb.height()), attrs)
+}
+
+// clipped - whether natricons of this shape have to be clipped
+func (s Shape) clipped() bool {
+	return s != "" && s != Square
+}
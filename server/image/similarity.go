@@ -0,0 +1,65 @@
+package image
+
+import (
+	"math"
+)
+
+// Weights of the traits in similarity scores, colors are what tells natricons apart at a glance
+const (
+	bodyColorWeight = 0.35
+	hairColorWeight = 0.25
+	bodyWeight      = 0.1
+	hairWeight      = 0.1
+	mouthWeight     = 0.1
+	eyeWeight       = 0.1
+)
+
+// ColorDistanceScale - CIEDE2000 distance at which colors count as nothing alike
+const ColorDistanceScale = 25.0
+
+// LookalikeScore - similarity score from which natricons are easily mistaken for each other, random natricons almost never reach it
+const LookalikeScore = 0.75
+
+// Similarity - how alike two natricons look
+type Similarity struct {
+	Score             float64  `json:"score"`               // 0 for nothing alike to 1 for identical
+	BodyColorDistance float64  `json:"body_color_distance"` // CIEDE2000 distance of body colors
+	HairColorDistance float64  `json:"hair_color_distance"` // CIEDE2000 distance of hair colors
+	SharedTraits      []string `json:"shared_traits"`       // Illustrations both natricons use, body, hair, mouth or eye
+	Lookalike         bool     `json:"lookalike"`
+}
+
+// colorSimilarity - similarity of colors at a CIEDE2000 distance, 1 for the same color
+func colorSimilarity(distance float64) float64 {
+	return 1 - math.Min(distance/ColorDistanceScale, 1)
+}
+
+// Compare - how alike natricons look, from trait overlap and the perceptual distance of their colors
+func Compare(a Accessories, b Accessories) Similarity {
+	s := Similarity{
+		BodyColorDistance: a.BodyColor.Distance(b.BodyColor),
+		HairColorDistance: a.HairColor.Distance(b.HairColor),
+		SharedTraits:      []string{},
+	}
+	s.Score = bodyColorWeight*colorSimilarity(s.BodyColorDistance) + hairColorWeight*colorSimilarity(s.HairColorDistance)
+	for _, trait := range []struct {
+		name   string
+		a, b   Asset
+		weight float64
+	}{
+		{"body", a.BodyAsset, b.BodyAsset, bodyWeight},
+		{"hair", a.HairAsset, b.HairAsset, hairWeight},
+		{"mouth", a.MouthAsset, b.MouthAsset, mouthWeight},
+		{"eye", a.EyeAsset, b.EyeAsset, eyeWeight},
+	} {
+		if trait.a.ID() == trait.b.ID() {
+			s.SharedTraits = append(s.SharedTraits, trait.name)
+			s.Score += trait.weight
+		}
+	}
+	s.Score = math.Round(s.Score*1000) / 1000
+	s.BodyColorDistance = round(s.BodyColorDistance)
+	s.HairColorDistance = round(s.HairColorDistance)
+	s.Lookalike = s.Score >= LookalikeScore
+	return s
+}
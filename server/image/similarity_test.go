@@ -0,0 +1,34 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/appditto/natricon/server/color"
+	"github.com/appditto/natricon/server/spc"
+)
+
+func TestCompare(t *testing.T) {
+	a := GetSpecificNatricon(spc.BTNone, false, nil, color.HTMLToRGBAlt("#6666ff"), color.HTMLToRGBAlt("#19ffc6"), 5, 15, 8, 10)
+	if s := Compare(a, a); s.Score != 1 || !s.Lookalike || len(s.SharedTraits) != 4 {
+		t.Errorf("Expected a natricon to look exactly like itself but got %v", s)
+	}
+
+	// Barely different colors with the same illustrations
+	b := GetSpecificNatricon(spc.BTNone, false, nil, color.HTMLToRGBAlt("#6868fc"), color.HTMLToRGBAlt("#19ffc6"), 5, 15, 8, 10)
+	if s := Compare(a, b); s.Score >= 1 || !s.Lookalike {
+		t.Errorf("Expected a lookalike but got %v", s)
+	}
+
+	// Opposite colors with a different body and eyes
+	c := GetSpecificNatricon(spc.BTNone, false, nil, color.HTMLToRGBAlt("#ffd000"), color.HTMLToRGBAlt("#a01030"), 6, 15, 8, 11)
+	s := Compare(a, c)
+	if s.Lookalike || s.Score != hairWeight+mouthWeight {
+		t.Errorf("Expected only hair and mouth to count but got %v", s)
+	}
+	if len(s.SharedTraits) != 2 || s.SharedTraits[0] != "hair" || s.SharedTraits[1] != "mouth" {
+		t.Errorf("Expected hair and mouth to be shared but got %v", s.SharedTraits)
+	}
+	if s.BodyColorDistance < ColorDistanceScale {
+		t.Errorf("Expected body colors to be far apart but got %f", s.BodyColorDistance)
+	}
+}
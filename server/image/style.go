@@ -0,0 +1,152 @@
+package image
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+)
+
+// Style - illustration set natricons are drawn with
+type Style string
+
+// DefaultStyle - style of the built-in illustrations
+const DefaultStyle Style = "flat"
+
+// stylePack - assets of a style by illustration type and file name
+type stylePack map[IllustrationType]map[string]*Asset
+
+// typedAssets - built-in assets of every illustration type, which style packs must mirror
+func (sm *assetManager) typedAssets() map[IllustrationType][][]Asset {
+	return map[IllustrationType][][]Asset{
+		Body:           {sm.bodyAssets},
+		BodyOutline:    {sm.bodyOutlineAssets},
+		Badge:          {sm.donorBadgeAssets, sm.exchBadgeAssets, sm.nodeBadgeAssets, sm.svcBadgeAssets},
+		Hair:           {sm.hairAssets},
+		HairBack:       {sm.hairBackAssets},
+		HairOutline:    {sm.hairOutlineAssets},
+		Mouth:          {sm.mouthAssets},
+		MouthOutline:   {sm.mouthOutlineAssets},
+		Eye:            {sm.eyeAssets},
+		Hat:            {sm.hatAssets},
+		HatOutline:     {sm.hatOutlineAssets},
+		Glasses:        {sm.glassesAssets},
+		Overlay:        {sm.overlayAssets},
+		OverlayOutline: {sm.overlayOutlineAssets},
+	}
+}
+
+// loadStylePack - load a style from a directory laid out like assets/illustrations
+// Every built-in asset needs a counterpart with the same file name, so traits pick the same illustrations in every style
+func (sm *assetManager) loadStylePack(dir string) (stylePack, error) {
+	pack := stylePack{}
+	for iType, lists := range sm.typedAssets() {
+		pack[iType] = map[string]*Asset{}
+		for _, list := range lists {
+			for _, a := range list {
+				styled := a
+				styled.IllustrationPath = path.Join(dir, string(iType), a.FileName)
+				contents, err := ioutil.ReadFile(styled.IllustrationPath)
+				if err != nil {
+					return nil, fmt.Errorf("Style is missing %s/%s", iType, a.FileName)
+				}
+				styled.SVGContents = contents
+				if err := styled.prepare(); err != nil {
+					return nil, err
+				}
+				pack[iType][a.FileName] = &styled
+			}
+		}
+	}
+	return pack, nil
+}
+
+// LoadStyles - load every style pack in a directory, each in a sub-directory named after the style
+// Must be called before natricons are generated, a missing directory is an error so misconfigured styles don't go unnoticed
+func LoadStyles(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("Styles directory %s does not exist", dir)
+	} else if err != nil {
+		return err
+	}
+	sm := GetAssets()
+	styles := map[Style]stylePack{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		style := Style(entry.Name())
+		if style == DefaultStyle {
+			return fmt.Errorf("Style %s is built in", style)
+		}
+		pack, err := sm.loadStylePack(path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("Failed to load style %s: %s", style, err)
+		}
+		styles[style] = pack
+	}
+	sm.styles = styles
+	return nil
+}
+
+// Styles - every style that can be rendered, the default first
+func Styles() []Style {
+	styles := []Style{}
+	for style := range GetAssets().styles {
+		styles = append(styles, style)
+	}
+	sort.Slice(styles, func(i, j int) bool { return styles[i] < styles[j] })
+	return append([]Style{DefaultStyle}, styles...)
+}
+
+// ParseStyle - get style by name
+func ParseStyle(name string) (Style, bool) {
+	for _, s := range Styles() {
+		if string(s) == name {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// WithStyle - accessories with every asset replaced by its counterpart in a style
+func (a Accessories) WithStyle(style Style) Accessories {
+	pack, ok := GetAssets().styles[style]
+	if !ok {
+		return a
+	}
+	styled := func(asset *Asset, iType IllustrationType) *Asset {
+		if asset == nil {
+			return nil
+		}
+		if s, ok := pack[iType][asset.FileName]; ok {
+			return s
+		}
+		return asset
+	}
+	a.BodyAsset = *styled(&a.BodyAsset, Body)
+	a.HairAsset = *styled(&a.HairAsset, Hair)
+	a.MouthAsset = *styled(&a.MouthAsset, Mouth)
+	a.EyeAsset = *styled(&a.EyeAsset, Eye)
+	a.BackHairAsset = styled(a.BackHairAsset, HairBack)
+	a.BodyOutlineAsset = styled(a.BodyOutlineAsset, BodyOutline)
+	a.HairOutlineAsset = styled(a.HairOutlineAsset, HairOutline)
+	a.MouthOutlineAsset = styled(a.MouthOutlineAsset, MouthOutline)
+	a.BadgeAsset = styled(a.BadgeAsset, Badge)
+	a.HatAsset = styled(a.HatAsset, Hat)
+	a.HatOutlineAsset = styled(a.HatOutlineAsset, HatOutline)
+	a.GlassesAsset = styled(a.GlassesAsset, Glasses)
+	overlays := make([]*Asset, len(a.OverlayAssets))
+	for i := range a.OverlayAssets {
+		overlays[i] = styled(a.OverlayAssets[i], Overlay)
+	}
+	a.OverlayAssets = overlays
+	overlayOutlines := make([]*Asset, len(a.OverlayOutlineAssets))
+	for i := range a.OverlayOutlineAssets {
+		overlayOutlines[i] = styled(a.OverlayOutlineAssets[i], OverlayOutline)
+	}
+	a.OverlayOutlineAssets = overlayOutlines
+	return a
+}
@@ -0,0 +1,72 @@
+package image
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/appditto/natricon/server/color"
+	"github.com/appditto/natricon/server/spc"
+)
+
+const styleMarker = `<path id="styled" d="M0 0h1v1H0z"/>`
+
+// writeStylePack - write a copy of the built-in assets with a marker in every body
+func writeStylePack(t *testing.T, dir string) {
+	for iType, lists := range GetAssets().typedAssets() {
+		if err := os.MkdirAll(path.Join(dir, string(iType)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, list := range lists {
+			for _, a := range list {
+				contents := a.SVGContents
+				if iType == Body {
+					contents = bytes.Replace(contents, []byte("</svg>"), []byte(styleMarker+"</svg>"), 1)
+				}
+				if err := ioutil.WriteFile(path.Join(dir, string(iType), a.FileName), contents, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+func TestWithStyle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "natricon-style")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeStylePack(t, path.Join(dir, "pixel"))
+	if err := LoadStyles(dir); err != nil {
+		t.Fatalf("Failed to load styles %s", err)
+	}
+	defer func() { GetAssets().styles = nil }()
+
+	if style, ok := ParseStyle("pixel"); !ok || style != "pixel" {
+		t.Errorf("Expected pixel style to be loaded, styles are %v", Styles())
+	}
+	accessories, _ := GetAccessoriesForHash(benchHash, spc.BTDonor, true, &color.RGB{R: 255, G: 255, B: 255})
+	styled := accessories.WithStyle("pixel")
+	if styled.BodyAsset.FileName != accessories.BodyAsset.FileName || styled.EyeAsset.FileName != accessories.EyeAsset.FileName {
+		t.Errorf("Styles must keep the same traits")
+	}
+	flat, _ := CombineSVG(accessories)
+	pixel, _ := CombineSVG(styled)
+	if bytes.Contains(flat, []byte("styled")) || !bytes.Contains(pixel, []byte("styled")) {
+		t.Errorf("Expected only the pixel natricon to be drawn with the style")
+	}
+
+	// Configured directories must exist
+	if err := LoadStyles(path.Join(dir, "missing")); err == nil {
+		t.Error("Expected error for missing styles directory")
+	}
+
+	// Packs need every built-in asset
+	os.Remove(path.Join(dir, "pixel", string(Eye), accessories.EyeAsset.FileName))
+	if err := LoadStyles(dir); err == nil {
+		t.Error("Expected error for incomplete style")
+	}
+}
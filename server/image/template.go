@@ -0,0 +1,318 @@
+package image
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// slot - attribute of an asset SVG that can be replaced when assembling a natricon
+type slot int
+
+const (
+	slotBodyColor     slot = iota // natricon-body, replaced with body color
+	slotHairColor                 // natricon-hair, replaced with hair color
+	slotMouthColor                // natricon-mouth, replaced with hair color on mouths
+	slotShadowOpacity             // natricon-shadow, replaced with opacity based on color
+	slotBlk299Opacity             // natricon-blk299, replaced on _blk299 assets
+	slotBlack                     // natricon-black, replaced with outline color or white on dark colors
+	slotWhite                     // natricon-white, replaced with badge outline color or lodBwReplacement
+	slotStrokeWidth               // natricon-outline-width, replaced with outline width
+	nSlots
+)
+
+// Prefix of class names that declare slots
+const slotClassPrefix = "natricon-"
+
+// Suffix of color slot class names that bind the stroke instead of the fill
+const slotStrokeSuffix = "-stroke"
+
+// Class name and attribute bound by every slot, indexed by slot
+var slotClasses = [nSlots]struct {
+	name string
+	attr string
+}{
+	slotBodyColor:     {"body", "fill"},
+	slotHairColor:     {"hair", "fill"},
+	slotMouthColor:    {"mouth", "fill"},
+	slotShadowOpacity: {"shadow", "fill-opacity"},
+	slotBlk299Opacity: {"blk299", "fill-opacity"},
+	slotBlack:         {"black", "fill"},
+	slotWhite:         {"white", "fill"},
+	slotStrokeWidth:   {"outline-width", "stroke-width"},
+}
+
+// slotValues - replacement for each slot, empty strings leave the attribute untouched
+type slotValues [nSlots]string
+
+// segment - literal text followed by a slotted attribute
+type segment struct {
+	text     string
+	slot     slot   // nSlots when the segment has no trailing attribute
+	attr     string // Name of the slotted attribute
+	original string // Slotted attribute as written in the asset
+	element  int    // Index of the element the slotted attribute belongs to
+}
+
+// svgTemplate - inner document of an asset SVG split around its slotted attributes
+type svgTemplate struct {
+	segments []segment
+}
+
+// attrSpan - attribute of a start tag and its position in the tag
+type attrSpan struct {
+	name  string
+	value string
+	start int
+	end   int
+}
+
+// element - start tag of an asset SVG and its position in the document
+type element struct {
+	offset int
+	attrs  []attrSpan
+}
+
+// compileTemplate - parse asset SVG and locate all of its slots
+// Slots are declared with natricon- classes, which are left out of the document, assets without any are used as they are
+func compileTemplate(svgContents []byte) (*svgTemplate, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(svgContents))
+	var elements []element
+	start, end, depth := -1, -1, 0
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			if depth == 0 && start < 0 {
+				start = int(decoder.InputOffset())
+			} else if depth > 0 {
+				elements = append(elements, element{offset: offset, attrs: scanAttrs(svgContents[offset:decoder.InputOffset()])})
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 && end < 0 {
+				end = offset
+			}
+		}
+	}
+	if start < 0 || end < 0 {
+		return nil, fmt.Errorf("Asset SVG has no root element")
+	}
+
+	t := &svgTemplate{}
+	last := start
+	for index, e := range elements {
+		slots, err := elementSlots(e.attrs)
+		if err != nil {
+			return nil, err
+		}
+		for i, a := range e.attrs {
+			if a.name == "class" && len(slotClassNames(e.attrs[i:i+1])) > 0 {
+				// Drop slot classes with the space before them, keeping any other class
+				text := strings.TrimRight(string(svgContents[last:e.offset+a.start]), " \t\r\n")
+				if classes := otherClasses(a.value); classes != "" {
+					text += fmt.Sprintf(" class=\"%s\"", classes)
+				}
+				t.segments = append(t.segments, segment{text: text, slot: nSlots})
+				last = e.offset + a.end
+				continue
+			} else if slots[i] == nSlots {
+				continue
+			}
+			t.segments = append(t.segments, segment{
+				text:     string(svgContents[last : e.offset+a.start]),
+				slot:     slots[i],
+				attr:     a.name,
+				original: string(svgContents[e.offset+a.start : e.offset+a.end]),
+				element:  index,
+			})
+			last = e.offset + a.end
+		}
+	}
+	t.segments = append(t.segments, segment{text: string(svgContents[last:end]), slot: nSlots})
+	return t, nil
+}
+
+// elementSlots - slot of every attribute of an element, nSlots for attributes without one
+func elementSlots(attrs []attrSpan) ([]slot, error) {
+	slots := make([]slot, len(attrs))
+	for i := range slots {
+		slots[i] = nSlots
+	}
+	bind := func(s slot, attr string) error {
+		for i, a := range attrs {
+			if a.name == attr {
+				slots[i] = s
+				return nil
+			}
+		}
+		return fmt.Errorf("Slot class %s%s requires a %s attribute", slotClassPrefix, slotClasses[s].name, attr)
+	}
+
+	for _, class := range slotClassNames(attrs) {
+		s, attr, err := parseSlotClass(class)
+		if err != nil {
+			return nil, err
+		}
+		if err := bind(s, attr); err != nil {
+			return nil, err
+		}
+	}
+	for i, a := range attrs {
+		if a.name == "style" && containsSlot(slots, i) {
+			// Slots are written as styles when using CSS variables
+			return nil, fmt.Errorf("Slotted elements can't have a style attribute")
+		}
+	}
+	return slots, nil
+}
+
+// slotClassNames - slot class names of an element without their prefix
+func slotClassNames(attrs []attrSpan) []string {
+	var names []string
+	for _, a := range attrs {
+		if a.name != "class" {
+			continue
+		}
+		for _, class := range strings.Fields(a.value) {
+			if strings.HasPrefix(class, slotClassPrefix) {
+				names = append(names, strings.TrimPrefix(class, slotClassPrefix))
+			}
+		}
+	}
+	return names
+}
+
+// otherClasses - classes of a class attribute that don't declare slots
+func otherClasses(value string) string {
+	var classes []string
+	for _, class := range strings.Fields(value) {
+		if !strings.HasPrefix(class, slotClassPrefix) {
+			classes = append(classes, class)
+		}
+	}
+	return strings.Join(classes, " ")
+}
+
+// parseSlotClass - slot and attribute of a class name without its prefix
+func parseSlotClass(name string) (slot, string, error) {
+	stroke := strings.HasSuffix(name, slotStrokeSuffix)
+	for s, c := range slotClasses {
+		if name == c.name {
+			return slot(s), c.attr, nil
+		} else if stroke && c.attr == "fill" && name == c.name+slotStrokeSuffix {
+			return slot(s), "stroke", nil
+		}
+	}
+	return nSlots, "", fmt.Errorf("Unknown slot class %s%s", slotClassPrefix, name)
+}
+
+func containsSlot(slots []slot, except int) bool {
+	for i, s := range slots {
+		if i != except && s != nSlots {
+			return true
+		}
+	}
+	return false
+}
+
+// has - whether any attribute of the template is bound to a slot
+func (t *svgTemplate) has(s slot) bool {
+	for _, seg := range t.segments {
+		if seg.slot == s {
+			return true
+		}
+	}
+	return false
+}
+
+// scanAttrs - locate the attributes of a well-formed start tag
+func scanAttrs(tag []byte) []attrSpan {
+	var attrs []attrSpan
+	isSpace := func(b byte) bool { return b == ' ' || b == '\t' || b == '\n' || b == '\r' }
+	// Skip element name
+	i := 1
+	for i < len(tag) && !isSpace(tag[i]) && tag[i] != '/' && tag[i] != '>' {
+		i++
+	}
+	for i < len(tag) {
+		for i < len(tag) && isSpace(tag[i]) {
+			i++
+		}
+		if i >= len(tag) || tag[i] == '/' || tag[i] == '>' {
+			break
+		}
+		start := i
+		for i < len(tag) && tag[i] != '=' && !isSpace(tag[i]) {
+			i++
+		}
+		name := string(tag[start:i])
+		for i < len(tag) && tag[i] != '\'' && tag[i] != '"' {
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		quote := tag[i]
+		i++
+		valueStart := i
+		for i < len(tag) && tag[i] != quote {
+			i++
+		}
+		attrs = append(attrs, attrSpan{name: name, value: string(tag[valueStart:i]), start: start, end: i + 1})
+		i++
+	}
+	return attrs
+}
+
+// cssVar - style declaration of a slotted attribute using a CSS variable, empty if it doesn't use one
+func (seg segment) cssVar(values *slotValues, vars *slotValues) string {
+	if seg.slot == nSlots || vars == nil || vars[seg.slot] == "" || values[seg.slot] == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:var(%s,%s)", seg.attr, vars[seg.slot], values[seg.slot])
+}
+
+// execute - write document with every slot filled in from values
+// Slots with a CSS variable in vars are written as styles, so they can be changed by the page embedding the SVG
+// Elements get a single style attribute with the declarations of all of their slots using CSS variables
+func (t *svgTemplate) execute(w io.Writer, values *slotValues, vars *slotValues) {
+	styled := -1 // Element whose style attribute was written
+	for i, seg := range t.segments {
+		if seg.cssVar(values, vars) != "" && seg.element == styled {
+			// Declared in the style attribute, drop the space before the attribute too
+			io.WriteString(w, strings.TrimRight(seg.text, " \t\r\n"))
+			continue
+		}
+		io.WriteString(w, seg.text)
+		if seg.slot == nSlots {
+			continue
+		}
+		v := values[seg.slot]
+		if v == "" {
+			io.WriteString(w, seg.original)
+		} else if declaration := seg.cssVar(values, vars); declaration != "" {
+			declarations := []string{declaration}
+			for _, next := range t.segments[i+1:] {
+				if next.slot != nSlots && next.element != seg.element {
+					break
+				} else if d := next.cssVar(values, vars); d != "" && next.element == seg.element {
+					declarations = append(declarations, d)
+				}
+			}
+			io.WriteString(w, fmt.Sprintf("style=\"%s\"", strings.Join(declarations, ";")))
+			styled = seg.element
+		} else {
+			io.WriteString(w, fmt.Sprintf("%s=\"%s\"", seg.attr, v))
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package image
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testDoc = `<path class="natricon-body" d="M0 0h1" fill="#00FFFF"/><path class="natricon-black natricon-shadow" d="M1 1h1" fill="black" fill-opacity="0.15"/><path class="natricon-white natricon-blk299" fill="white" fill-opacity="0.299"/><path class="natricon-hair" id="white" fill="#FF0000"/><path class="natricon-mouth natricon-black-stroke" fill="#FFFF00" stroke="black"/>`
+
+// testDoc as rendered, without its slot classes
+const testRendered = `<path d="M0 0h1" fill="#00FFFF"/><path d="M1 1h1" fill="black" fill-opacity="0.15"/><path fill="white" fill-opacity="0.299"/><path id="white" fill="#FF0000"/><path fill="#FFFF00" stroke="black"/>`
+
+func compileTestTemplate(t *testing.T, doc string) *svgTemplate {
+	tpl, err := compileTemplate([]byte(`<svg viewBox="0 0 512 512" xmlns="http://www.w3.org/2000/svg">` + doc + `</svg>`))
+	if err != nil {
+		t.Fatalf("Failed to compile template %s", err)
+	}
+	return tpl
+}
+
+func TestTemplateUnchanged(t *testing.T) {
+	var b bytes.Buffer
+	compileTestTemplate(t, testDoc).execute(&b, &slotValues{}, nil)
+	if b.String() != testRendered {
+		t.Errorf("Expected %s but got %s", testRendered, b.String())
+	}
+}
+
+func TestTemplateFill(t *testing.T) {
+	var b bytes.Buffer
+	compileTestTemplate(t, testDoc).execute(&b, &slotValues{
+		slotBodyColor:     "#123456",
+		slotShadowOpacity: "0.200000",
+		slotWhite:         lodBwReplacement,
+		slotBlack:         "white",
+	}, nil)
+	expected := strings.ReplaceAll(testRendered, "#00FFFF", "#123456")
+	expected = strings.ReplaceAll(expected, "fill-opacity=\"0.15\"", "fill-opacity=\"0.200000\"")
+	expected = strings.ReplaceAll(expected, "fill=\"white\"", "fill=\""+lodBwReplacement+"\"")
+	expected = strings.ReplaceAll(expected, "black", "white")
+	if b.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, b.String())
+	}
+}
+
+func TestTemplateSlotClasses(t *testing.T) {
+	// Placeholder colors are left alone once an asset declares its slots
+	doc := `<path class="natricon-body" fill="#ABCDEF"/><path fill="#00FFFF" stroke="black"/><g class="shade natricon-hair natricon-black-stroke" fill="#000" stroke="#111"/>`
+	var b bytes.Buffer
+	compileTestTemplate(t, doc).execute(&b, &slotValues{
+		slotBodyColor: "#123456",
+		slotHairColor: "#654321",
+		slotBlack:     "white",
+	}, nil)
+	expected := `<path fill="#123456"/><path fill="#00FFFF" stroke="black"/><g class="shade" fill="#654321" stroke="white"/>`
+	if b.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, b.String())
+	}
+
+	for _, invalid := range []string{
+		`<path class="natricon-body"/>`,
+		`<path class="natricon-eyes" fill="#000"/>`,
+		`<path class="natricon-shadow-stroke" fill-opacity="0.2"/>`,
+		`<path class="natricon-body" fill="#000" style="opacity:0.5"/>`,
+	} {
+		if _, err := compileTemplate([]byte(`<svg>` + invalid + `</svg>`)); err == nil {
+			t.Errorf("Expected error for %s", invalid)
+		}
+	}
+}
+
+func TestTemplateCSSVars(t *testing.T) {
+	var b bytes.Buffer
+	compileTestTemplate(t, `<path class="natricon-body natricon-shadow" fill="#00FFFF" fill-opacity="0.15"/>`).execute(&b,
+		&slotValues{slotBodyColor: "#123456", slotShadowOpacity: "0.2"},
+		&slotValues{slotBodyColor: cssVarBody},
+	)
+	expected := `<path style="fill:var(--natricon-body,#123456)" fill-opacity="0.2"/>`
+	if b.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, b.String())
+	}
+}
+
+func TestTemplateCSSVarsFillAndStroke(t *testing.T) {
+	// Slots of an element share a single style attribute, XML doesn't allow two
+	doc := `<path class="natricon-hair natricon-shadow natricon-hair-stroke" fill="#FF0000" fill-opacity="0.15" d="M0 0h1" stroke="#FF0000"/><path class="natricon-hair" fill="#FF0000"/>`
+	var b bytes.Buffer
+	compileTestTemplate(t, doc).execute(&b,
+		&slotValues{slotHairColor: "#654321", slotShadowOpacity: "0.2"},
+		&slotValues{slotHairColor: cssVarHair},
+	)
+	expected := `<path style="fill:var(--natricon-hair,#654321);stroke:var(--natricon-hair,#654321)" fill-opacity="0.2" d="M0 0h1"/><path style="fill:var(--natricon-hair,#654321)"/>`
+	if b.String() != expected {
+		t.Errorf("Expected %s but got %s", expected, b.String())
+	}
+}
+
+func TestCompileTemplate(t *testing.T) {
+	if _, err := compileTemplate([]byte("<svg")); err == nil {
+		t.Error("Expected error for malformed SVG")
+	}
+}
+
+func TestTemplateWithoutSlots(t *testing.T) {
+	// Colors of assets without slot classes are the artist's, even the ones that used to be placeholders
+	doc := `<path fill="#00FFFF" stroke="black" stroke-width="16"/><path fill="white" fill-opacity="0.15"/>`
+	var b bytes.Buffer
+	compileTestTemplate(t, doc).execute(&b, &slotValues{
+		slotBodyColor:   "#123456",
+		slotBlack:       "white",
+		slotWhite:       lodBwReplacement,
+		slotStrokeWidth: "4",
+	}, nil)
+	if b.String() != doc {
+		t.Errorf("Expected %s but got %s", doc, b.String())
+	}
+}
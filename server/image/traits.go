@@ -0,0 +1,89 @@
+package image
+
+import (
+	"math"
+
+	"github.com/appditto/natricon/server/color"
+)
+
+// AssetTraits - identifies an asset used by a natricon
+type AssetTraits struct {
+	ID       int    `json:"id"`
+	FileName string `json:"file_name"`
+}
+
+// HSBTraits - hue in degrees, saturation and brightness in percent
+type HSBTraits struct {
+	H float64 `json:"h"`
+	S float64 `json:"s"`
+	B float64 `json:"b"`
+}
+
+// HSLTraits - hue in degrees, saturation and lightness in percent
+type HSLTraits struct {
+	H float64 `json:"h"`
+	S float64 `json:"s"`
+	L float64 `json:"l"`
+}
+
+// ColorTraits - a color of a natricon in the color spaces clients commonly use
+type ColorTraits struct {
+	Hex string    `json:"hex"`
+	HSB HSBTraits `json:"hsb"`
+	HSL HSLTraits `json:"hsl"`
+}
+
+// Traits - everything that was decided when generating a natricon
+type Traits struct {
+	Sex         Sex          `json:"sex"`
+	BodyColor   ColorTraits  `json:"body_color"`
+	HairColor   ColorTraits  `json:"hair_color"`
+	Body        AssetTraits  `json:"body"`
+	Hair        AssetTraits  `json:"hair"`
+	Mouth       AssetTraits  `json:"mouth"`
+	Eye         AssetTraits  `json:"eye"`
+	BackHair    *AssetTraits `json:"back_hair"`
+	Accessories []string     `json:"accessories,omitempty"`
+}
+
+// round - round to one decimal
+func round(f float64) float64 {
+	return math.Round(f*10) / 10
+}
+
+func getAssetTraits(asset Asset) AssetTraits {
+	return AssetTraits{ID: asset.ID(), FileName: asset.FileName}
+}
+
+func getColorTraits(c color.RGB) ColorTraits {
+	hsb := c.ToHSB()
+	hsl := c.ToHSL()
+	return ColorTraits{
+		Hex: c.ToHTML(true),
+		HSB: HSBTraits{H: round(hsb.H), S: round(hsb.S * 100), B: round(hsb.B * 100)},
+		HSL: HSLTraits{H: round(hsl.H), S: round(hsl.S * 100), L: round(hsl.L * 100)},
+	}
+}
+
+// GetTraits - describe the assets and colors of a natricon
+func GetTraits(accessories Accessories) Traits {
+	traits := Traits{
+		Sex:       accessories.Sex(),
+		BodyColor: getColorTraits(accessories.BodyColor),
+		HairColor: getColorTraits(accessories.HairColor),
+		Body:      getAssetTraits(accessories.BodyAsset),
+		Hair:      getAssetTraits(accessories.HairAsset),
+		Mouth:     getAssetTraits(accessories.MouthAsset),
+		Eye:       getAssetTraits(accessories.EyeAsset),
+	}
+	if accessories.BackHairAsset != nil {
+		backHair := getAssetTraits(*accessories.BackHairAsset)
+		traits.BackHair = &backHair
+	}
+	for _, a := range []*Asset{accessories.HatAsset, accessories.GlassesAsset} {
+		if a != nil {
+			traits.Accessories = append(traits.Accessories, a.AccessoryName())
+		}
+	}
+	return traits
+}
@@ -0,0 +1,36 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/appditto/natricon/server/color"
+	"github.com/appditto/natricon/server/spc"
+)
+
+func TestAssetID(t *testing.T) {
+	for fileName, expected := range map[string]int{"10_sq-84.svg": 10, "26.svg": 26, "donor_b1_b2.svg": -1} {
+		if id := (Asset{FileName: fileName}).ID(); id != expected {
+			t.Errorf("Expected ID %d for %s but got %d", expected, fileName, id)
+		}
+	}
+}
+
+func TestGetTraits(t *testing.T) {
+	accessories := GetSpecificNatricon(spc.BTNone, false, nil, color.HTMLToRGBAlt("#6666ff"), color.HTMLToRGBAlt("#19ffc6"), 5, 15, 8, 10)
+	traits := GetTraits(accessories)
+	if traits.Body.ID != 5 || traits.Hair.ID != 15 || traits.Mouth.ID != 8 || traits.Eye.ID != 10 {
+		t.Errorf("Unexpected asset IDs %v %v %v %v", traits.Body, traits.Hair, traits.Mouth, traits.Eye)
+	}
+	if traits.BodyColor.Hex != "#6666ff" {
+		t.Errorf("Expected body color #6666ff but got %s", traits.BodyColor.Hex)
+	}
+	if traits.BodyColor.HSB.H != 240 || traits.BodyColor.HSB.S != 60 || traits.BodyColor.HSB.B != 100 {
+		t.Errorf("Unexpected HSB %v", traits.BodyColor.HSB)
+	}
+	if traits.BodyColor.HSL.S != 100 || traits.BodyColor.HSL.L != 70 {
+		t.Errorf("Unexpected HSL %v", traits.BodyColor.HSL)
+	}
+	if traits.Sex != accessories.Sex() {
+		t.Errorf("Expected sex %s but got %s", accessories.Sex(), traits.Sex)
+	}
+}
@@ -0,0 +1,231 @@
+package image
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	goimage "image"
+	gocolor "image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/appditto/natricon/server/color"
+	"github.com/appditto/natricon/server/render"
+	"github.com/appditto/natricon/server/spc"
+	"github.com/appditto/natricon/server/utils"
+)
+
+var update = flag.Bool("update", false, "Regenerate test vectors and golden images instead of checking them")
+
+const vectorsFile = "testdata/vectors.json"
+const goldenDir = "testdata/golden"
+const goldenSize = 128
+const rasterTolerance = 16           // Largest channel difference of pixels that count as unchanged
+const rasterMaxChangedPixels = 0.001 // Fraction of pixels that can change before the image counts as changed
+
+// vectorOptions - options of a test vector, named like the query parameters of the API
+type vectorOptions struct {
+	Badge        spc.BadgeType `json:"badge,omitempty"`
+	Outline      bool          `json:"outline,omitempty"`
+	OutlineColor string        `json:"outline_color,omitempty"`
+	Version      Version       `json:"v"`
+}
+
+// vector - natricon generated for an address with a seed, nonce and options
+type vector struct {
+	Address   string        `json:"address"`
+	Seed      string        `json:"seed"`
+	Nonce     int           `json:"nonce"` // -1 when no nonce is applied
+	Options   vectorOptions `json:"options"`
+	Hash      string        `json:"hash"`
+	Traits    Traits        `json:"traits"`
+	SVGSHA256 string        `json:"svg_sha256"`
+}
+
+// vectorFile - published test vectors, for reimplementations to check they generate the same natricons
+type vectorFile struct {
+	Description string   `json:"description"`
+	Vectors     []vector `json:"vectors"`
+}
+
+const vectorsDescription = "hash is the hex SHA-256 of the hex public key of the address followed by the seed, " +
+	"the public key is prefixed with the nonce and a colon when nonce isn't -1. " +
+	"svg_sha256 is the hex SHA-256 of the SVG output."
+
+// Inputs of the test vectors, their outputs are generated with -update
+var vectorInputs = []vector{
+	{Address: "nano_3oi4g4ksytzdg9se6c4qx1ri4ftq5mi66w9r9fhi5mzn9ppymz54u6apu5od", Seed: "1234567890", Nonce: -1},
+	{Address: "nano_1bi4kxz5tsdr6ayr6iiunxnbncbxaikcb8ssu1dmnshnxcon76hkxijux3og", Seed: "1234567890", Nonce: -1},
+	{Address: "nano_14a4r6317ipcf7yw4yxddq76fc46su8kpgzwkk878emhbjjdsh85etn97tai", Seed: "1234567890", Nonce: 3},
+	{Address: "nano_15neqybjdewwkydsoq9ow9b971gbbcsktu9teigjphaazzg9da5fzzwos8fe", Seed: "1234567890", Nonce: -1, Options: vectorOptions{Badge: spc.BTDonor}},
+	{Address: "nano_3t7xjrmycrq6mu9g7qcmz3uxehf3k7nen77niwudy81ek3tffyz93xaw59rp", Seed: "1234567890", Nonce: -1, Options: vectorOptions{Badge: spc.BTNode, Outline: true, OutlineColor: "#FFFFFF"}},
+	{Address: "nano_3q8qyz4h5d9t4ffxfnec7btskh74cim69jpngws7pym6zppipds8xum833sh", Seed: "1234567890", Nonce: -1, Options: vectorOptions{Outline: true, OutlineColor: "#000000"}},
+	{Address: "nano_1ei5cbzwea4f6xi8uj74z67uprw53c67s3qyoqfxcurhuyqycr9c6oxnpdgu", Seed: "natricon", Nonce: -1, Options: vectorOptions{Badge: spc.BTService}},
+	{Address: "nano_3q387s49atmgxuy8bcp941d7bwgrt9tfu58fc5xteadqc6na9uf5s1bibmut", Seed: "natricon", Nonce: 1, Options: vectorOptions{Badge: spc.BTExchange, Outline: true, OutlineColor: "#00FF00"}},
+	{Address: "nano_3oi4g4ksytzdg9se6c4qx1ri4ftq5mi66w9r9fhi5mzn9ppymz54u6apu5od", Seed: "1234567890", Nonce: -1, Options: vectorOptions{Version: V2}},
+	{Address: "nano_1bi4kxz5tsdr6ayr6iiunxnbncbxaikcb8ssu1dmnshnxcon76hkxijux3og", Seed: "1234567890", Nonce: -1, Options: vectorOptions{Version: V2}},
+	{Address: "nano_14a4r6317ipcf7yw4yxddq76fc46su8kpgzwkk878emhbjjdsh85etn97tai", Seed: "1234567890", Nonce: 3, Options: vectorOptions{Badge: spc.BTNode, Version: V2}},
+	{Address: "nano_1ei5cbzwea4f6xi8uj74z67uprw53c67s3qyoqfxcurhuyqycr9c6oxnpdgu", Seed: "natricon", Nonce: -1, Options: vectorOptions{Outline: true, OutlineColor: "#FFFFFF", Version: V2}},
+}
+
+// generate - fill in the outputs of a vector from its inputs, returns the SVG
+func (v *vector) generate() ([]byte, error) {
+	if v.Options.Version == 0 {
+		v.Options.Version = DefaultVersion
+	}
+	hashKey := utils.AddressToPub(v.Address)
+	if v.Nonce != -1 {
+		hashKey = fmt.Sprintf("%d:%s", v.Nonce, hashKey)
+	}
+	v.Hash = utils.PKSha256(hashKey, v.Seed)
+	var outlineColor *color.RGB
+	if v.Options.OutlineColor != "" {
+		parsed, err := color.ParseCSS(v.Options.OutlineColor)
+		if err != nil {
+			return nil, err
+		}
+		outlineColor = &parsed
+	}
+	badge := v.Options.Badge
+	if badge == "" {
+		badge = spc.BTNone
+	}
+	accessories, err := GetAccessoriesForHashVersion(v.Hash, v.Options.Version, badge, v.Options.Outline, outlineColor)
+	if err != nil {
+		return nil, err
+	}
+	v.Traits = GetTraits(accessories)
+	svg, err := CombineSVG(accessories)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(svg)
+	v.SVGSHA256 = hex.EncodeToString(digest[:])
+	return svg, nil
+}
+
+// readVectors - read published test vectors, regenerating them first with -update
+func readVectors(t *testing.T) []vector {
+	if *update {
+		file := vectorFile{Description: vectorsDescription}
+		for _, v := range vectorInputs {
+			if _, err := v.generate(); err != nil {
+				t.Fatal(err)
+			}
+			file.Vectors = append(file.Vectors, v)
+		}
+		data, _ := json.MarshalIndent(file, "", "  ")
+		if err := os.MkdirAll(path.Dir(vectorsFile), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(vectorsFile, append(data, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := ioutil.ReadFile(vectorsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file vectorFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	return file.Vectors
+}
+
+func TestVectors(t *testing.T) {
+	for i, expected := range readVectors(t) {
+		actual := expected
+		if _, err := actual.generate(); err != nil {
+			t.Fatal(err)
+		}
+		expectedJSON, _ := json.Marshal(expected)
+		actualJSON, _ := json.Marshal(actual)
+		if !bytes.Equal(expectedJSON, actualJSON) {
+			t.Errorf("Vector %d changed\nexpected %s\ngot      %s", i, expectedJSON, actualJSON)
+		}
+	}
+}
+
+func TestRasterRegression(t *testing.T) {
+	renderer, err := render.New("native")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer renderer.Close()
+	for i, v := range readVectors(t) {
+		svg, err := v.generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := renderer.Rasterize(svg, goldenSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := path.Join(goldenDir, fmt.Sprintf("vector-%02d.png", i))
+		if *update {
+			if err := os.MkdirAll(goldenDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			var b bytes.Buffer
+			if err := png.Encode(&b, actual); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(golden, b.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		f, err := os.Open(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		diff, changed := rasterDiff(expected, actual)
+		if float64(changed) > rasterMaxChangedPixels*goldenSize*goldenSize {
+			diffPath := path.Join(os.TempDir(), fmt.Sprintf("natricon-diff-vector-%02d.png", i))
+			if f, err := os.Create(diffPath); err == nil {
+				png.Encode(f, diff)
+				f.Close()
+			}
+			t.Errorf("Vector %d looks different from %s in %d pixels, see %s", i, golden, changed, diffPath)
+		}
+	}
+}
+
+// rasterDiff - image of the pixels that changed in red over a faded copy of the expected image, and how many changed
+func rasterDiff(expected goimage.Image, actual *goimage.RGBA) (*goimage.RGBA, int) {
+	bounds := actual.Bounds()
+	diff := goimage.NewRGBA(bounds)
+	changed := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			e := gocolor.RGBAModel.Convert(expected.At(x, y)).(gocolor.RGBA)
+			a := actual.RGBAAt(x, y)
+			if channelDelta(e.R, a.R) > rasterTolerance || channelDelta(e.G, a.G) > rasterTolerance || channelDelta(e.B, a.B) > rasterTolerance || channelDelta(e.A, a.A) > rasterTolerance {
+				changed++
+				diff.SetRGBA(x, y, gocolor.RGBA{R: 255, A: 255})
+			} else {
+				diff.SetRGBA(x, y, gocolor.RGBA{R: e.R / 4, G: e.G / 4, B: e.B / 4, A: e.A / 4})
+			}
+		}
+	}
+	return diff, changed
+}
+
+func channelDelta(a uint8, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
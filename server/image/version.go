@@ -0,0 +1,68 @@
+package image
+
+import (
+	"strconv"
+)
+
+// Version - version of the generation algorithm, pinning which assets natricons pick from and how
+// Natricons are identities, so a released version must keep generating the same natricons forever
+type Version int
+
+const (
+	V1 Version = 1 // Uniform picks from the assets available at launch, MT19937 seeded with a few hex digits per trait
+	V2 Version = 2 // Weighted picks, every trait drawn from its own SHA-256 stream over the whole hash
+)
+
+// LatestVersion - newest version of the generation algorithm
+const LatestVersion = V2
+
+// DefaultVersion - version natricons are generated with unless one is requested
+const DefaultVersion = V1
+
+// Versions - every version that can be generated, oldest first
+var Versions = []Version{V1, V2}
+
+// algorithm - selection rules of a version, never changed once released
+type algorithm struct {
+	weighted bool // Favor assets with a higher manifest weight instead of picking uniformly
+	streams  bool // Draw traits from trait streams instead of MT19937 seeded with hex digits of the hash
+	badges   bool // Place badges on the badge_bodies of their manifest entries instead of matching b<body> in their file names
+}
+
+var algorithms = map[Version]algorithm{
+	V1: {},
+	V2: {weighted: true, streams: true, badges: true},
+}
+
+// ParseVersion - parse version of the generation algorithm
+func ParseVersion(s string) (Version, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false
+	}
+	if _, ok := algorithms[Version(n)]; !ok {
+		return 0, false
+	}
+	return Version(n), true
+}
+
+// snapshot - assets a version picks from, the ones added in it or before, in manifest order
+// New assets can be added anywhere in a manifest without changing the natricons of older versions
+func (v Version) snapshot(assets []Asset) []Asset {
+	var ret []Asset
+	for _, a := range assets {
+		if a.since() <= v {
+			ret = append(ret, a)
+		}
+	}
+	return ret
+}
+
+// pick - pick index of an asset with the RNG following the rules of the version
+// extra outcomes that aren't assets can be added, picking them returns len(assets)
+func (v Version) pick(r randSource, assets []Asset, extra int) int {
+	if algorithms[v].weighted {
+		return pickWeighted(r, assets, extra)
+	}
+	return int(r.Int31n(int32(len(assets) + extra)))
+}
@@ -0,0 +1,87 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/appditto/natricon/server/spc"
+)
+
+// Digest of every asset V1 picks from, changing it changes existing natricons
+const v1SnapshotDigest = "c93d2cced6c56181298f80aa6d7f031d62027279ea77c3d66421db941b3536b0"
+
+// snapshotDigest - digest of the names and contents of the assets a version picks from, in order
+func snapshotDigest(v Version) string {
+	hasher := sha256.New()
+	typed := GetAssets().typedAssets()
+	for _, iType := range illustrationTypes {
+		for _, list := range typed[iType] {
+			for _, a := range v.snapshot(list) {
+				contents := sha256.Sum256(a.SVGContents)
+				hasher.Write([]byte(string(iType) + "/" + a.FileName + ":" + hex.EncodeToString(contents[:]) + "\n"))
+			}
+		}
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+func TestV1SnapshotPinned(t *testing.T) {
+	if digest := snapshotDigest(V1); digest != v1SnapshotDigest {
+		t.Errorf("Assets of version 1 changed to %s, add new assets with a since of a newer version instead", digest)
+	}
+}
+
+func TestSnapshotSkipsNewerAssets(t *testing.T) {
+	assets := []Asset{{FileName: "1_a.svg"}, {FileName: "3_c.svg", Since: 2}, {FileName: "2_b.svg", Since: V1}}
+	if snapshot := V1.snapshot(assets); len(snapshot) != 2 || snapshot[0].FileName != "1_a.svg" || snapshot[1].FileName != "2_b.svg" {
+		t.Errorf("Expected version 1 to pick from 1_a.svg and 2_b.svg but got %v", snapshot)
+	}
+	if snapshot := Version(2).snapshot(assets); len(snapshot) != 3 {
+		t.Errorf("Expected version 2 to pick from every asset but got %d", len(snapshot))
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	if v, ok := ParseVersion("1"); !ok || v != V1 {
+		t.Errorf("Expected version 1 to parse")
+	}
+	if v, ok := ParseVersion("2"); !ok || v != V2 {
+		t.Errorf("Expected version 2 to parse")
+	}
+	for _, invalid := range []string{"", "0", "-1", "99", "v1"} {
+		if _, ok := ParseVersion(invalid); ok {
+			t.Errorf("Expected version %q to be invalid", invalid)
+		}
+	}
+}
+
+// SVGs of badged version 1 natricons on bodies 1 and 2, which get the badge of bodies 11 to 21, as the first release rendered them
+func TestV1BadgeOnBody1And2(t *testing.T) {
+	for _, test := range []struct {
+		hash     string
+		outline  bool
+		body     string
+		expected string
+	}{
+		{"8234c26068973b7fd16d2a70c27a300fa9b0ddc4b5f40cc85fdaba8992e47a23", false, "2_sq-52.svg", "c716e2e4099968ce06a6617c3d812e8cdf2161c747f1bf4f816faca323f54769"},
+		{"8234c26068973b7fd16d2a70c27a300fa9b0ddc4b5f40cc85fdaba8992e47a23", true, "2_sq-52.svg", "ac51ab14069117f738535646ad933f6a514b943eaf7a93b98d9cb89d1cbb7e98"},
+		{"9b565035fdd705c8fdea811c34520a04578160a8f9a782d4ff7b6be7ebc75936", false, "1_sq-48.svg", "473742eacc4df1ec51c1766d9e4f8f233fcc9d309f8490146fcb88a58ed7a9ca"},
+		{"9b565035fdd705c8fdea811c34520a04578160a8f9a782d4ff7b6be7ebc75936", true, "1_sq-48.svg", "99932875d6962ad34ad371a8a1f613c157f21ad134952dffbdd20829f66657e1"},
+	} {
+		accessories, err := GetAccessoriesForHashVersion(test.hash, V1, spc.BTDonor, test.outline, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accessories.BodyAsset.FileName != test.body {
+			t.Fatalf("Expected body %s got %s", test.body, accessories.BodyAsset.FileName)
+		}
+		svg, err := CombineSVG(accessories)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digest := sha256.Sum256(svg); hex.EncodeToString(digest[:]) != test.expected {
+			t.Errorf("Version 1 natricon %s with outline %v changed", test.hash, test.outline)
+		}
+	}
+}
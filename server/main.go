@@ -8,16 +8,17 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/appditto/natricon/server/cache"
 	"github.com/appditto/natricon/server/controller"
 	"github.com/appditto/natricon/server/image"
 	"github.com/appditto/natricon/server/net"
+	"github.com/appditto/natricon/server/render"
 	"github.com/appditto/natricon/server/spc"
 	"github.com/appditto/natricon/server/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/glog"
 	socketio "github.com/googollee/go-socket.io"
 	"github.com/jasonlvhit/gocron"
-	"gopkg.in/gographics/imagick.v3/imagick"
 )
 
 func CorsMiddleware() gin.HandlerFunc {
@@ -60,21 +61,31 @@ func main() {
 	// Get seed from env
 	seed := utils.GetEnv("NATRICON_SEED", "1234567890")
 	// Parse server options
-	loadFiles := flag.Bool("load-files", false, "Print assets as GO arrays")
 	testBodyDist := flag.Bool("test-bd", false, "Test body distribution")
 	testHairDist := flag.Bool("test-hd", false, "Test hair distribution")
+	testCollisions := flag.Int("test-collisions", -1, "Compare how often this many random natricons collide with every version of the algorithm")
 	randomFiles := flag.Int("rand-files", -1, "Generate this many random SVGs and output to randsvg folder")
 
 	serverHost := flag.String("host", "127.0.0.1", "Host to listen on")
 	serverPort := flag.Int("port", 8080, "Port to listen on")
 	rpcUrl := flag.String("rpc-url", "", "Optional URL to use for nano RPC Client")
 	wsUrl := flag.String("nano-ws-url", "", "Nano WS Url to use for tracking donation account")
+	renderCacheMB := flag.Int("render-cache-mb", 64, "Size of the in-memory cache of rendered natricons in MB, 0 to disable")
+	renderCacheRedis := flag.Bool("render-cache-redis", false, "Also cache rendered natricons in redis")
+	assetsDir := flag.String("assets-dir", "", "Optional directory laid out like assets/illustrations to load illustrations from instead of the built-in ones")
+	stylesDir := flag.String("styles-dir", "", "Optional directory with a sub-directory of illustrations for each alternate style")
+	seasonsConfig := flag.String("seasons-config", "", "Optional JSON file with seasonal overlays to draw between dates")
+	rendererName := flag.String("renderer", render.DefaultBackend, fmt.Sprintf("Backend to use for PNG/WEBP conversion %v", render.Backends()))
 	flag.Parse()
 
-	if *loadFiles {
-		LoadAssetsToArray()
-		return
-	} else if *randomFiles > 0 {
+	// Load illustrations from a directory, so they can be changed without rebuilding
+	if *assetsDir != "" {
+		if err := image.LoadAssets(*assetsDir); err != nil {
+			glog.Fatal(err)
+		}
+	}
+
+	if *randomFiles > 0 {
 		fmt.Printf("Generating %d files in ./randsvg", *randomFiles)
 		RandFiles(*randomFiles, seed)
 		return
@@ -86,6 +97,9 @@ func main() {
 	} else if *testHairDist {
 		controller.TestHairDistribution(seed)
 		return
+	} else if *testCollisions > 0 {
+		controller.TestCollisions(seed, *testCollisions)
+		return
 	}
 
 	var rpcClient *net.RPCClient
@@ -94,9 +108,34 @@ func main() {
 		rpcClient = &net.RPCClient{Url: *rpcUrl}
 	}
 
-	// Setup magickwand
-	imagick.Initialize()
-	defer imagick.Terminate()
+	// Load alternate styles, only the built-in style is available without them
+	if *stylesDir != "" {
+		if err := image.LoadStyles(*stylesDir); err != nil {
+			glog.Fatal(err)
+		}
+		glog.Infof("Loaded styles %v", image.Styles())
+	}
+
+	// Load seasonal overlays
+	if *seasonsConfig != "" {
+		if err := image.LoadSeasons(*seasonsConfig); err != nil {
+			glog.Fatal(err)
+		}
+	}
+
+	// Sample rarity of the default version in the background, so rarity requests don't wait for it
+	go func() {
+		if _, err := image.GetRarityTable(image.DefaultVersion); err != nil {
+			glog.Errorf("Failed to sample rarity table %s", err)
+		}
+	}()
+
+	// Setup renderer
+	renderer, err := render.New(*rendererName)
+	if err != nil {
+		glog.Fatal(err)
+	}
+	defer renderer.Close()
 
 	// Setup router
 	router := gin.Default()
@@ -119,13 +158,22 @@ func main() {
 	router.GET("/socket.io/*any", gin.WrapH(sio))
 	router.POST("/socket.io/*any", gin.WrapH(sio))
 
+	// Setup render cache
+	var renderCache *cache.RenderCache
+	if *renderCacheMB > 0 {
+		renderCache = cache.NewRenderCache(int64(*renderCacheMB)*1024*1024, *renderCacheRedis)
+	}
+
 	// Setup channel for stats processing job
-	statsChan := make(chan *gin.Context, 100)
+	statsChan := make(chan *controller.StatsMessage, 100)
 
 	// Setup natricon controller
 	natriconController := controller.NatriconController{
 		Seed:         seed,
 		StatsChannel: &statsChan,
+		Renderer:     renderer,
+		Cache:        renderCache,
+		Content:      image.ContentDigest(),
 	}
 	// Setup nano controller
 	nanoController := controller.NanoController{
@@ -137,6 +185,13 @@ func main() {
 	// V1 API
 	router.GET("/api/v1/nano", natriconController.GetNano)
 	router.GET("/api/v1/nano/nonce", natriconController.GetNonce)
+	router.GET("/api/v1/nano/traits", natriconController.GetTraits)
+	router.GET("/api/v1/nano/rarity", natriconController.GetRarity)
+	router.POST("/api/v1/nano/batch", natriconController.PostBatch)
+	router.GET("/api/v1/nano/compare", natriconController.GetCompare)
+	router.POST("/api/v1/nano/compare", natriconController.PostCompare)
+	// V2 API
+	router.GET("/api/v2/nano/*path", natriconController.GetNanoV2)
 	// Stats
 	router.GET("/api/v1/nano/stats", controller.Stats)
 	if gin.IsDebugging() {
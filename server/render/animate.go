@@ -0,0 +1,272 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"sort"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+const gifAlphaThreshold = 128 // Pixels with lower alpha are transparent in GIFs
+
+// EncodeAnimation - encode frames as an animation that loops forever
+// delays are the time each frame is shown in milliseconds
+func EncodeAnimation(frames []*image.RGBA, delays []int, format ImageFormat) ([]byte, error) {
+	if len(frames) == 0 || len(frames) != len(delays) {
+		return nil, errors.New("Every frame requires a delay")
+	}
+	var b bytes.Buffer
+	var err error
+	switch format {
+	case GIF:
+		err = encodeGIF(&b, frames, delays)
+	case APNG:
+		err = encodeAPNG(&b, frames, delays)
+	case WEBP:
+		err = encodeAnimatedWEBP(&b, frames, delays)
+	default:
+		return nil, errors.New("Unsupported animation format")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// gifPalette - palette of the most common colors of all frames, index 0 is transparent
+func gifPalette(frames []*image.RGBA) color.Palette {
+	counts := map[color.RGBA]int{}
+	for _, frame := range frames {
+		for i := 0; i+3 < len(frame.Pix); i += 4 {
+			if frame.Pix[i+3] < gifAlphaThreshold {
+				continue
+			}
+			counts[unpremultiply(frame.Pix[i:i+4])]++
+		}
+	}
+	colors := make([]color.RGBA, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	// Sort by popularity, ties broken by value so palettes are deterministic
+	sort.Slice(colors, func(i, j int) bool {
+		if counts[colors[i]] != counts[colors[j]] {
+			return counts[colors[i]] > counts[colors[j]]
+		}
+		ci, cj := colors[i], colors[j]
+		return uint32(ci.R)<<16|uint32(ci.G)<<8|uint32(ci.B) < uint32(cj.R)<<16|uint32(cj.G)<<8|uint32(cj.B)
+	})
+	palette := color.Palette{color.RGBA{}}
+	for _, c := range colors {
+		if len(palette) == 256 {
+			break
+		}
+		palette = append(palette, c)
+	}
+	return palette
+}
+
+// unpremultiply - opaque color of a premultiplied RGBA pixel
+func unpremultiply(pix []uint8) color.RGBA {
+	a := uint32(pix[3])
+	return color.RGBA{
+		R: uint8(uint32(pix[0]) * 255 / a),
+		G: uint8(uint32(pix[1]) * 255 / a),
+		B: uint8(uint32(pix[2]) * 255 / a),
+		A: 255,
+	}
+}
+
+func encodeGIF(b *bytes.Buffer, frames []*image.RGBA, delays []int) error {
+	palette := gifPalette(frames)
+	anim := &gif.GIF{LoopCount: 0}
+	// Exact lookups for colors in the palette, nearest match otherwise
+	lookup := map[color.RGBA]uint8{}
+	for i, c := range palette[1:] {
+		lookup[c.(color.RGBA)] = uint8(i + 1)
+	}
+	for i, frame := range frames {
+		bounds := frame.Bounds()
+		paletted := image.NewPaletted(bounds, palette)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				offset := frame.PixOffset(x, y)
+				if frame.Pix[offset+3] < gifAlphaThreshold {
+					continue
+				}
+				c := unpremultiply(frame.Pix[offset : offset+4])
+				index, ok := lookup[c]
+				if !ok {
+					index = uint8(palette[1:].Index(c) + 1)
+					lookup[c] = index
+				}
+				paletted.Pix[paletted.PixOffset(x, y)] = index
+			}
+		}
+		anim.Image = append(anim.Image, paletted)
+		// GIF delays are in hundredths of a second
+		anim.Delay = append(anim.Delay, (delays[i]+5)/10)
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+	return gif.EncodeAll(b, anim)
+}
+
+// writePNGChunk - write a PNG chunk with its length and checksum
+func writePNGChunk(b *bytes.Buffer, chunkType string, data []byte) {
+	binary.Write(b, binary.BigEndian, uint32(len(data)))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunkType))
+	crc.Write(data)
+	b.WriteString(chunkType)
+	b.Write(data)
+	binary.Write(b, binary.BigEndian, crc.Sum32())
+}
+
+// compressPNGFrame - zlib compressed, unfiltered 8-bit RGBA scanlines of a frame
+func compressPNGFrame(frame *image.RGBA) ([]byte, error) {
+	var b bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&b, zlib.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	bounds := frame.Bounds()
+	row := make([]byte, 1+4*bounds.Dx())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(frame.RGBAAt(x, y)).(color.NRGBA)
+			i := 1 + 4*(x-bounds.Min.X)
+			row[i], row[i+1], row[i+2], row[i+3] = c.R, c.G, c.B, c.A
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func encodeAPNG(b *bytes.Buffer, frames []*image.RGBA, delays []int) error {
+	width, height := uint32(frames[0].Bounds().Dx()), uint32(frames[0].Bounds().Dy())
+	b.WriteString("\x89PNG\r\n\x1a\n")
+	// 8-bit RGBA, no interlacing
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8], ihdr[9] = 8, 6
+	writePNGChunk(b, "IHDR", ihdr)
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(frames)))
+	writePNGChunk(b, "acTL", actl)
+	sequence := uint32(0)
+	for i, frame := range frames {
+		if uint32(frame.Bounds().Dx()) != width || uint32(frame.Bounds().Dy()) != height {
+			return errors.New("All frames must be the same size")
+		}
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], sequence)
+		binary.BigEndian.PutUint32(fctl[4:], width)
+		binary.BigEndian.PutUint32(fctl[8:], height)
+		// Delay as a fraction in milliseconds, dispose to background, replace previous frame
+		binary.BigEndian.PutUint16(fctl[20:], uint16(delays[i]))
+		binary.BigEndian.PutUint16(fctl[22:], 1000)
+		fctl[24], fctl[25] = 1, 0
+		writePNGChunk(b, "fcTL", fctl)
+		sequence++
+		data, err := compressPNGFrame(frame)
+		if err != nil {
+			return err
+		}
+		// The first frame doubles as the still image for decoders without APNG support
+		if i == 0 {
+			writePNGChunk(b, "IDAT", data)
+		} else {
+			fdat := make([]byte, 4, 4+len(data))
+			binary.BigEndian.PutUint32(fdat, sequence)
+			writePNGChunk(b, "fdAT", append(fdat, data...))
+			sequence++
+		}
+	}
+	writePNGChunk(b, "IEND", nil)
+	return nil
+}
+
+// put24 - write little endian 24-bit integer used by WEBP animation chunks
+func put24(b []byte, v int) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+// webpChunk - get payload of the first chunk with given type from a WEBP file
+func webpChunk(data []byte, chunkType string) ([]byte, error) {
+	for offset := 12; offset+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if offset+8+size > len(data) {
+			break
+		}
+		if string(data[offset:offset+4]) == chunkType {
+			return data[offset+8 : offset+8+size], nil
+		}
+		// Chunks are padded to an even size
+		offset += 8 + size + size%2
+	}
+	return nil, errors.New("Missing WEBP chunk " + chunkType)
+}
+
+// writeWEBPChunk - write a RIFF chunk with padding
+func writeWEBPChunk(b *bytes.Buffer, chunkType string, data []byte) {
+	b.WriteString(chunkType)
+	binary.Write(b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+	if len(data)%2 == 1 {
+		b.WriteByte(0)
+	}
+}
+
+func encodeAnimatedWEBP(b *bytes.Buffer, frames []*image.RGBA, delays []int) error {
+	width, height := frames[0].Bounds().Dx(), frames[0].Bounds().Dy()
+	var chunks bytes.Buffer
+	// Extended format header with animation and alpha flags
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x02 | 0x10
+	put24(vp8x[4:], width-1)
+	put24(vp8x[7:], height-1)
+	writeWEBPChunk(&chunks, "VP8X", vp8x)
+	// Transparent background, loop forever
+	writeWEBPChunk(&chunks, "ANIM", make([]byte, 6))
+	for i, frame := range frames {
+		if frame.Bounds().Dx() != width || frame.Bounds().Dy() != height {
+			return errors.New("All frames must be the same size")
+		}
+		var still bytes.Buffer
+		if err := nativewebp.Encode(&still, frame, nil); err != nil {
+			return err
+		}
+		vp8l, err := webpChunk(still.Bytes(), "VP8L")
+		if err != nil {
+			return err
+		}
+		anmf := make([]byte, 16)
+		put24(anmf[6:], width-1)
+		put24(anmf[9:], height-1)
+		put24(anmf[12:], delays[i])
+		// Do not blend with the previous frame, dispose to background
+		anmf[15] = 0x02 | 0x01
+		var frameData bytes.Buffer
+		frameData.Write(anmf)
+		writeWEBPChunk(&frameData, "VP8L", vp8l)
+		writeWEBPChunk(&chunks, "ANMF", frameData.Bytes())
+	}
+	b.WriteString("RIFF")
+	binary.Write(b, binary.LittleEndian, uint32(4+chunks.Len()))
+	b.WriteString("WEBP")
+	b.Write(chunks.Bytes())
+	return nil
+}
@@ -0,0 +1,84 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func testFrames() []*image.RGBA {
+	var frames []*image.RGBA
+	for _, c := range []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}} {
+		frame := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		for y := 4; y < 12; y++ {
+			for x := 4; x < 12; x++ {
+				frame.SetRGBA(x, y, c)
+			}
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func TestEncodeGIF(t *testing.T) {
+	encoded, err := EncodeAnimation(testFrames(), []int{100, 200, 300}, GIF)
+	if err != nil {
+		t.Fatalf("Failed to encode GIF %s", err)
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("Invalid GIF %s", err)
+	}
+	if len(anim.Image) != 3 || anim.Delay[1] != 20 {
+		t.Errorf("Expected 3 frames with delay 20 but got %d frames with delays %v", len(anim.Image), anim.Delay)
+	}
+	if _, _, _, a := anim.Image[0].At(0, 0).RGBA(); a != 0 {
+		t.Error("Expected transparent corner")
+	}
+	if r, g, _, _ := anim.Image[1].At(8, 8).RGBA(); r != 0 || g != 0xffff {
+		t.Error("Expected green center in second frame")
+	}
+}
+
+func TestEncodeAPNG(t *testing.T) {
+	encoded, err := EncodeAnimation(testFrames(), []int{100, 200, 300}, APNG)
+	if err != nil {
+		t.Fatalf("Failed to encode APNG %s", err)
+	}
+	// Decoders without APNG support show the first frame
+	still, err := png.Decode(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("Invalid PNG %s", err)
+	}
+	if r, _, _, a := still.At(8, 8).RGBA(); r != 0xffff || a != 0xffff {
+		t.Error("Expected red center in still image")
+	}
+	if bytes.Count(encoded, []byte("fcTL")) != 3 || bytes.Count(encoded, []byte("fdAT")) != 2 {
+		t.Error("Expected 3 frame controls and 2 frame data chunks")
+	}
+}
+
+func TestEncodeAnimatedWEBP(t *testing.T) {
+	encoded, err := EncodeAnimation(testFrames(), []int{100, 200, 300}, WEBP)
+	if err != nil {
+		t.Fatalf("Failed to encode WEBP %s", err)
+	}
+	if _, err := webpChunk(encoded, "ANIM"); err != nil {
+		t.Error("Expected ANIM chunk")
+	}
+	if bytes.Count(encoded, []byte("ANMF")) != 3 {
+		t.Error("Expected 3 frames")
+	}
+}
+
+func TestEncodeAnimationErrors(t *testing.T) {
+	if _, err := EncodeAnimation(testFrames(), []int{100}, GIF); err == nil {
+		t.Error("Expected error for missing delays")
+	}
+	if _, err := EncodeAnimation(testFrames(), []int{100, 100, 100}, PNG); err == nil {
+		t.Error("Expected error for PNG animation")
+	}
+}
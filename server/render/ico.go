@@ -0,0 +1,45 @@
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+)
+
+// ICOSizes - resolutions included in ICO files, from smallest to largest
+var ICOSizes = []uint{16, 32, 48, 64}
+
+const icoHeaderSize = 6 // Reserved, type and number of images
+const icoEntrySize = 16 // Directory entry of each image
+
+// EncodeICO - encode images as a multi-resolution ICO file
+// Images are stored as PNGs, which every ICO reader since Windows Vista supports
+func EncodeICO(images []*image.RGBA) ([]byte, error) {
+	if len(images) == 0 {
+		return nil, errors.New("ICO files require at least one image")
+	}
+	var header, data bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, [3]uint16{0, 1, uint16(len(images))})
+	offset := icoHeaderSize + icoEntrySize*len(images)
+	for _, img := range images {
+		width, height := img.Bounds().Dx(), img.Bounds().Dy()
+		if width > 256 || height > 256 {
+			return nil, errors.New("ICO images can't be larger than 256 pixels")
+		}
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, img); err != nil {
+			return nil, err
+		}
+		// Width and height of 256 are stored as 0, colors and reserved are unused
+		header.Write([]byte{byte(width), byte(height), 0, 0})
+		// 1 color plane with 32 bits per pixel
+		binary.Write(&header, binary.LittleEndian, [2]uint16{1, 32})
+		binary.Write(&header, binary.LittleEndian, [2]uint32{uint32(encoded.Len()), uint32(offset)})
+		offset += encoded.Len()
+		data.Write(encoded.Bytes())
+	}
+	header.Write(data.Bytes())
+	return header.Bytes(), nil
+}
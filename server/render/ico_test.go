@@ -0,0 +1,37 @@
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestEncodeICO(t *testing.T) {
+	var images []*image.RGBA
+	for _, size := range ICOSizes {
+		images = append(images, image.NewRGBA(image.Rect(0, 0, int(size), int(size))))
+	}
+	ico, err := EncodeICO(images)
+	if err != nil {
+		t.Fatalf("EncodeICO failed %s", err)
+	}
+	if !bytes.Equal(ico[:4], []byte{0, 0, 1, 0}) || int(binary.LittleEndian.Uint16(ico[4:])) != len(ICOSizes) {
+		t.Fatalf("Invalid ICO header %v", ico[:6])
+	}
+	for i, size := range ICOSizes {
+		entry := ico[icoHeaderSize+i*icoEntrySize:]
+		if uint(entry[0]) != size || uint(entry[1]) != size {
+			t.Errorf("Expected %dx%d entry but got %dx%d", size, size, entry[0], entry[1])
+		}
+		length, offset := binary.LittleEndian.Uint32(entry[8:]), binary.LittleEndian.Uint32(entry[12:])
+		img, err := png.Decode(bytes.NewReader(ico[offset : offset+length]))
+		if err != nil {
+			t.Fatalf("Entry %d is not a valid PNG %s", i, err)
+		}
+		if uint(img.Bounds().Dx()) != size {
+			t.Errorf("Expected %d pixel image but got %d", size, img.Bounds().Dx())
+		}
+	}
+}
@@ -0,0 +1,121 @@
+//go:build magick
+// +build magick
+
+package render
+
+import (
+	"errors"
+	"image"
+	"strings"
+
+	"gopkg.in/gographics/imagick.v3/imagick"
+)
+
+// Default size of SVGs produced by image.CombineSVG, used to compute density
+const svgSize = 512
+
+func init() {
+	backends["magick"] = newMagickRenderer
+	DefaultBackend = "magick"
+}
+
+// magickRenderer - renderer backed by ImageMagick, requires cgo and MagickWand libraries
+type magickRenderer struct{}
+
+func newMagickRenderer() Renderer {
+	imagick.Initialize()
+	return &magickRenderer{}
+}
+
+// readSvg - read SVG into a new magick wand at the requested size
+func (mr *magickRenderer) readSvg(svgData []byte, size uint) (*imagick.MagickWand, error) {
+	mw := imagick.NewMagickWand()
+	mw.SetImageFormat("SVG")
+	pixelWand := imagick.NewPixelWand()
+	defer pixelWand.Destroy()
+	pixelWand.SetColor("none")
+	mw.SetBackgroundColor(pixelWand)
+	mw.SetImageUnits(imagick.RESOLUTION_PIXELS_PER_INCH)
+	density := 96.0 * float64(size) / float64(svgSize)
+	mw.SetResolution(density, density)
+	err := mw.ReadImageBlob(svgData)
+	if err != nil {
+		mw.Destroy()
+		return nil, err
+	}
+	return mw, nil
+}
+
+func (mr *magickRenderer) Rasterize(svgData []byte, size uint) (*image.RGBA, error) {
+	mw, err := mr.readSvg(svgData, size)
+	if err != nil {
+		return nil, err
+	}
+	defer mw.Destroy()
+	pixels, err := mw.ExportImagePixels(0, 0, size, size, "RGBA", imagick.PIXEL_CHAR)
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := pixels.([]byte)
+	if !ok {
+		return nil, errors.New("Unexpected pixel format")
+	}
+	// ImageMagick exports straight alpha, image.RGBA is premultiplied
+	img := image.NewRGBA(image.Rect(0, 0, int(size), int(size)))
+	for i := 0; i+3 < len(raw) && i+3 < len(img.Pix); i += 4 {
+		a := uint32(raw[i+3])
+		img.Pix[i] = uint8(uint32(raw[i]) * a / 255)
+		img.Pix[i+1] = uint8(uint32(raw[i+1]) * a / 255)
+		img.Pix[i+2] = uint8(uint32(raw[i+2]) * a / 255)
+		img.Pix[i+3] = uint8(a)
+	}
+	return img, nil
+}
+
+func (mr *magickRenderer) Convert(svgData []byte, format ImageFormat, size uint, quality int) ([]byte, error) {
+	switch format {
+	case PNG, WEBP, JPEG, AVIF:
+	default:
+		// Formats ImageMagick doesn't write the way we want them
+		img, err := mr.Rasterize(svgData, size)
+		if err != nil {
+			return nil, err
+		}
+		return Encode(img, format, quality)
+	}
+	mw, err := mr.readSvg(svgData, size)
+	if err != nil {
+		return nil, err
+	}
+	defer mw.Destroy()
+	if !format.Lossy() {
+		mw.SetImageCompression(imagick.COMPRESSION_NO)
+		mw.SetImageCompressionQuality(100)
+	} else {
+		if quality == 0 {
+			quality = DefaultJPEGQuality
+			if format == AVIF {
+				quality = DefaultAVIFQuality
+			}
+		}
+		mw.SetImageCompressionQuality(uint(quality))
+		if format == JPEG {
+			// JPEGs have no alpha channel
+			background := imagick.NewPixelWand()
+			defer background.Destroy()
+			background.SetColor("white")
+			mw.SetImageBackgroundColor(background)
+			mw.SetImageAlphaChannel(imagick.ALPHA_CHANNEL_REMOVE)
+		}
+	}
+	mw.SetImageFormat(strings.ToUpper(string(format)))
+	return mw.GetImageBlob(), nil
+}
+
+func (mr *magickRenderer) Close() {
+	imagick.Terminate()
+}
+
+func (mr *magickRenderer) Name() string {
+	return "magick"
+}
@@ -0,0 +1,436 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/math/fixed"
+)
+
+const minStrokeWidth = 1.0 // Minimum stroke width in pixels
+
+// nativeRenderer - pure Go renderer for the SVG subset generated by image.CombineSVG
+// Supports svg, g, path, rect, circle and ellipse elements with fill, stroke, opacity and transform attributes
+// and clip paths defined in defs that are referenced by groups
+type nativeRenderer struct{}
+
+func newNativeRenderer() Renderer {
+	return &nativeRenderer{}
+}
+
+func (nr *nativeRenderer) Rasterize(svgData []byte, size uint) (*image.RGBA, error) {
+	if size == 0 {
+		return nil, errors.New("Invalid size")
+	}
+	img := image.NewRGBA(image.Rect(0, 0, int(size), int(size)))
+	if err := newRasterizer(img).draw(svgData); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+func (nr *nativeRenderer) Convert(svgData []byte, format ImageFormat, size uint, quality int) ([]byte, error) {
+	img, err := nr.Rasterize(svgData, size)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(img, format, quality)
+}
+
+func (nr *nativeRenderer) Close() {}
+
+func (nr *nativeRenderer) Name() string {
+	return "native"
+}
+
+// style - presentation attributes inherited by child elements
+type style struct {
+	fill          color.Color // nil when fill is none
+	fillOpacity   float64
+	evenOdd       bool
+	stroke        color.Color // nil when stroke is none
+	strokeOpacity float64
+	strokeWidth   float64
+	lineCap       rasterx.CapFunc
+	lineJoin      rasterx.JoinMode
+	miterLimit    float64
+	opacity       float64
+	transform     rasterx.Matrix2D
+}
+
+// SVG initial values
+var defaultStyle = style{
+	fill:          color.Black,
+	fillOpacity:   1.0,
+	strokeOpacity: 1.0,
+	strokeWidth:   1.0,
+	lineCap:       rasterx.ButtCap,
+	lineJoin:      rasterx.Miter,
+	miterLimit:    4.0,
+	opacity:       1.0,
+	transform:     rasterx.Identity,
+}
+
+type rasterizer struct {
+	img     *image.RGBA
+	scanner *rasterx.ScannerGV
+	dasher  *rasterx.Dasher
+}
+
+func newRasterizer(img *image.RGBA) *rasterizer {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	scanner := rasterx.NewScannerGV(w, h, img, img.Bounds())
+	return &rasterizer{
+		img:     img,
+		scanner: scanner,
+		dasher:  rasterx.NewDasher(w, h, scanner),
+	}
+}
+
+// clipShape - shape of a clip path with the transform it was defined with
+type clipShape struct {
+	path      rasterx.Path
+	transform rasterx.Matrix2D
+}
+
+// clipGroup - group drawn on its own canvas, masked with a clip path onto its parent when it ends
+type clipGroup struct {
+	parent *rasterizer
+	shapes []clipShape
+	depth  int // Depth of the style stack inside the group
+}
+
+// draw - walk the SVG document and draw every supported shape
+func (r *rasterizer) draw(svgData []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(svgData))
+	stack := []style{defaultStyle}
+	skipDepth := 0
+	defsDepth := 0
+	clipPaths := map[string][]clipShape{}
+	clipID := "" // ID of the clip path being defined
+	var groups []clipGroup
+	cur := r
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		switch el := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+			s := stack[len(stack)-1]
+			switch el.Name.Local {
+			case "svg":
+				s.transform = r.viewBoxTransform(el)
+			case "g", "path", "rect", "circle", "ellipse", "defs", "clipPath":
+			default:
+				// Unsupported element, ignore it and all of its children
+				skipDepth = 1
+				continue
+			}
+			if err := s.apply(el.Attr); err != nil {
+				return err
+			}
+			stack = append(stack, s)
+			switch el.Name.Local {
+			case "defs":
+				defsDepth++
+			case "clipPath":
+				clipID = getAttr(el, "id")
+			case "g":
+				// Groups referencing a known clip path are drawn on their own canvas
+				if shapes, ok := clipPaths[clipReference(getAttr(el, "clip-path"))]; ok {
+					groups = append(groups, clipGroup{parent: cur, shapes: shapes, depth: len(stack)})
+					cur = newRasterizer(image.NewRGBA(r.img.Bounds()))
+				}
+			case "path", "rect", "circle", "ellipse":
+				if clipID != "" {
+					path, err := shapePath(el)
+					if err != nil {
+						return err
+					}
+					clipPaths[clipID] = append(clipPaths[clipID], clipShape{path: path, transform: s.transform})
+				} else if defsDepth == 0 {
+					if err := cur.drawShape(el, s); err != nil {
+						return err
+					}
+				}
+			}
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			if len(groups) > 0 && groups[len(groups)-1].depth == len(stack) {
+				group := groups[len(groups)-1]
+				group.parent.drawClipped(cur.img, group.shapes)
+				cur = group.parent
+				groups = groups[:len(groups)-1]
+			}
+			switch el.Name.Local {
+			case "defs":
+				defsDepth--
+			case "clipPath":
+				clipID = ""
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return nil
+}
+
+// clipReference - get ID of the clip path referenced like url(#id)
+func clipReference(v string) string {
+	v = strings.TrimSpace(v)
+	if !strings.HasPrefix(v, "url(#") || !strings.HasSuffix(v, ")") {
+		return ""
+	}
+	return v[len("url(#") : len(v)-1]
+}
+
+// drawClipped - draw image over the canvas where it is covered by the clip shapes
+// The mask is rasterized like any other fill, so clipped edges are antialiased
+func (r *rasterizer) drawClipped(src *image.RGBA, shapes []clipShape) {
+	mask := newRasterizer(image.NewRGBA(r.img.Bounds()))
+	filler := &mask.dasher.Filler
+	for _, shape := range shapes {
+		filler.Clear()
+		shape.path.AddTo(&rasterx.MatrixAdder{M: shape.transform, Adder: filler})
+		filler.SetColor(color.White)
+		filler.Draw()
+	}
+	draw.DrawMask(r.img, r.img.Bounds(), src, src.Bounds().Min, mask.img, mask.img.Bounds().Min, draw.Over)
+}
+
+// viewBoxTransform - map the viewBox of the root element onto the canvas, centered like xMidYMid meet
+func (r *rasterizer) viewBoxTransform(el xml.StartElement) rasterx.Matrix2D {
+	w, h := float64(r.img.Bounds().Dx()), float64(r.img.Bounds().Dy())
+	vb := parseFloats(getAttr(el, "viewBox"))
+	if len(vb) != 4 || vb[2] <= 0 || vb[3] <= 0 {
+		return rasterx.Identity
+	}
+	scale := math.Min(w/vb[2], h/vb[3])
+	offsetX := (w - vb[2]*scale) / 2
+	offsetY := (h - vb[3]*scale) / 2
+	return rasterx.Identity.Translate(offsetX, offsetY).Scale(scale, scale).Translate(-vb[0], -vb[1])
+}
+
+// shapePath - get outline of a shape element in user space
+func shapePath(el xml.StartElement) (rasterx.Path, error) {
+	var path rasterx.Path
+	switch el.Name.Local {
+	case "path":
+		cursor := &oksvg.PathCursor{}
+		if err := cursor.CompilePath(getAttr(el, "d")); err != nil {
+			return nil, err
+		}
+		path = cursor.Path
+	case "rect":
+		x, y := getFloatAttr(el, "x"), getFloatAttr(el, "y")
+		w, h := getFloatAttr(el, "width"), getFloatAttr(el, "height")
+		rx, ry := getFloatAttr(el, "rx"), getFloatAttr(el, "ry")
+		if rx == 0 {
+			rx = ry
+		} else if ry == 0 {
+			ry = rx
+		}
+		if rx == 0 {
+			rasterx.AddRect(x, y, x+w, y+h, 0, &path)
+		} else {
+			rasterx.AddRoundRect(x, y, x+w, y+h, rx, ry, 0, rasterx.RoundGap, &path)
+		}
+	case "circle":
+		rasterx.AddCircle(getFloatAttr(el, "cx"), getFloatAttr(el, "cy"), getFloatAttr(el, "r"), &path)
+	case "ellipse":
+		rasterx.AddEllipse(getFloatAttr(el, "cx"), getFloatAttr(el, "cy"), getFloatAttr(el, "rx"), getFloatAttr(el, "ry"), 0, &path)
+	}
+	return path, nil
+}
+
+// drawShape - fill and stroke a single shape element
+func (r *rasterizer) drawShape(el xml.StartElement, s style) error {
+	path, err := shapePath(el)
+	if err != nil {
+		return err
+	}
+	if s.fill != nil {
+		r.dasher.Clear()
+		filler := &r.dasher.Filler
+		filler.SetWinding(!s.evenOdd)
+		path.AddTo(&rasterx.MatrixAdder{M: s.transform, Adder: filler})
+		filler.SetColor(rasterx.ApplyOpacity(s.fill, s.fillOpacity*s.opacity))
+		filler.Draw()
+		filler.SetWinding(true)
+	}
+	if s.stroke != nil && s.strokeWidth > 0 {
+		r.dasher.Clear()
+		// Stroke width is given in user space, scale it to the canvas
+		scale := math.Sqrt(math.Abs(s.transform.A*s.transform.D - s.transform.B*s.transform.C))
+		// Strokes thinner than a pixel fade away on small canvases
+		width := math.Max(s.strokeWidth*scale, minStrokeWidth)
+		r.dasher.SetStroke(
+			fixed.Int26_6(width*64),
+			fixed.Int26_6(s.miterLimit*64),
+			s.lineCap, nil, rasterx.RoundGap, s.lineJoin, nil, 0,
+		)
+		path.AddTo(&rasterx.MatrixAdder{M: s.transform, Adder: r.dasher})
+		r.dasher.SetColor(rasterx.ApplyOpacity(s.stroke, s.strokeOpacity*s.opacity))
+		r.dasher.Draw()
+	}
+	return nil
+}
+
+// apply - apply presentation attributes of an element to the style
+func (s *style) apply(attrs []xml.Attr) error {
+	var err error
+	for _, attr := range attrs {
+		v := strings.TrimSpace(attr.Value)
+		switch attr.Name.Local {
+		case "fill":
+			s.fill, err = parseColor(v)
+		case "fill-opacity":
+			s.fillOpacity, err = strconv.ParseFloat(v, 64)
+		case "fill-rule":
+			s.evenOdd = v == "evenodd"
+		case "stroke":
+			s.stroke, err = parseColor(v)
+		case "stroke-opacity":
+			s.strokeOpacity, err = strconv.ParseFloat(v, 64)
+		case "stroke-width":
+			s.strokeWidth, err = strconv.ParseFloat(v, 64)
+		case "stroke-miterlimit":
+			s.miterLimit, err = strconv.ParseFloat(v, 64)
+		case "stroke-linecap":
+			switch v {
+			case "round":
+				s.lineCap = rasterx.RoundCap
+			case "square":
+				s.lineCap = rasterx.SquareCap
+			default:
+				s.lineCap = rasterx.ButtCap
+			}
+		case "stroke-linejoin":
+			switch v {
+			case "round":
+				s.lineJoin = rasterx.Round
+			case "bevel":
+				s.lineJoin = rasterx.Bevel
+			default:
+				s.lineJoin = rasterx.Miter
+			}
+		case "opacity":
+			var opacity float64
+			opacity, err = strconv.ParseFloat(v, 64)
+			s.opacity *= opacity
+		case "transform":
+			var m rasterx.Matrix2D
+			m, err = parseTransform(v)
+			s.transform = s.transform.Mult(m)
+		}
+		if err != nil {
+			return fmt.Errorf("Invalid %s attribute %s: %v", attr.Name.Local, attr.Value, err)
+		}
+	}
+	return nil
+}
+
+// parseColor - parse an SVG paint, returns nil for none
+func parseColor(v string) (color.Color, error) {
+	if v == "currentColor" {
+		return color.Black, nil
+	}
+	return oksvg.ParseSVGColor(v)
+}
+
+// parseTransform - parse an SVG transform list
+func parseTransform(v string) (rasterx.Matrix2D, error) {
+	m := rasterx.Identity
+	for _, fn := range strings.Split(v, ")") {
+		fn = strings.TrimSpace(strings.TrimLeft(fn, " ,"))
+		if fn == "" {
+			continue
+		}
+		parts := strings.SplitN(fn, "(", 2)
+		if len(parts) != 2 {
+			return m, errors.New("Malformed transform")
+		}
+		args := parseFloats(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "matrix":
+			if len(args) != 6 {
+				return m, errors.New("matrix requires 6 arguments")
+			}
+			m = m.Mult(rasterx.Matrix2D{A: args[0], B: args[1], C: args[2], D: args[3], E: args[4], F: args[5]})
+		case "translate":
+			if len(args) == 1 {
+				m = m.Translate(args[0], 0)
+			} else if len(args) == 2 {
+				m = m.Translate(args[0], args[1])
+			} else {
+				return m, errors.New("translate requires 1 or 2 arguments")
+			}
+		case "scale":
+			if len(args) == 1 {
+				m = m.Scale(args[0], args[0])
+			} else if len(args) == 2 {
+				m = m.Scale(args[0], args[1])
+			} else {
+				return m, errors.New("scale requires 1 or 2 arguments")
+			}
+		case "rotate":
+			if len(args) == 1 {
+				m = m.Rotate(args[0] * math.Pi / 180)
+			} else if len(args) == 3 {
+				m = m.Translate(args[1], args[2]).Rotate(args[0]*math.Pi/180).Translate(-args[1], -args[2])
+			} else {
+				return m, errors.New("rotate requires 1 or 3 arguments")
+			}
+		default:
+			return m, fmt.Errorf("Unsupported transform %s", parts[0])
+		}
+	}
+	return m, nil
+}
+
+// parseFloats - parse a comma or space separated list of numbers
+func parseFloats(v string) []float64 {
+	var ret []float64
+	for _, f := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' }) {
+		parsed, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil
+		}
+		ret = append(ret, parsed)
+	}
+	return ret
+}
+
+func getAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func getFloatAttr(el xml.StartElement, name string) float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSuffix(getAttr(el, name), "px"), 64)
+	return f
+}
@@ -0,0 +1,114 @@
+package render
+
+import (
+	"bytes"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+const testSvg = `<svg viewBox="0 0 512 512" xmlns="http://www.w3.org/2000/svg"><g id="body"><rect x="128" y="128" width="256" height="256" rx="48" fill="#ff0000"/><path d="M0 0H64V64H0z" fill="#000" fill-opacity=".5"/></g><g id="outline"><circle cx="448" cy="448" r="32" fill="none" stroke="#00f" stroke-width="16"/></g></svg>`
+
+func TestNativeRasterize(t *testing.T) {
+	r, _ := New("native")
+	img, err := r.Rasterize([]byte(testSvg), 128)
+	if err != nil {
+		t.Fatalf("Rasterize failed %s", err)
+	}
+	if img.Bounds().Dx() != 128 || img.Bounds().Dy() != 128 {
+		t.Errorf("Expected 128x128 image but got %dx%d", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+	// Center of the rect
+	if c := img.RGBAAt(64, 64); c.R != 255 || c.G != 0 || c.B != 0 || c.A != 255 {
+		t.Errorf("Expected opaque red at center but got %v", c)
+	}
+	// Outside of every shape
+	if c := img.RGBAAt(100, 20); c.A != 0 {
+		t.Errorf("Expected transparent pixel but got %v", c)
+	}
+	// Half transparent black square
+	if c := img.RGBAAt(8, 8); c.A < 126 || c.A > 129 || c.R != 0 {
+		t.Errorf("Expected half transparent black but got %v", c)
+	}
+	// Inside of stroked circle is not filled
+	if c := img.RGBAAt(112, 112); c.A != 0 {
+		t.Errorf("Expected unfilled circle but got %v", c)
+	}
+	// On the stroke
+	if c := img.RGBAAt(112, 104); c.B != 255 || c.A != 255 {
+		t.Errorf("Expected blue stroke but got %v", c)
+	}
+}
+
+func TestNativeClipPath(t *testing.T) {
+	clipped := `<svg viewBox="0 0 512 512"><defs><clipPath id="shape"><circle cx="256" cy="256" r="256"/></clipPath></defs><g clip-path="url(#shape)"><rect width="512" height="512" fill="#0f0"/></g></svg>`
+	r, _ := New("native")
+	img, err := r.Rasterize([]byte(clipped), 128)
+	if err != nil {
+		t.Fatalf("Rasterize failed %s", err)
+	}
+	// Clip path definitions aren't drawn, corners are outside of the circle
+	if c := img.RGBAAt(2, 2); c.A != 0 {
+		t.Errorf("Expected transparent corner but got %v", c)
+	}
+	if c := img.RGBAAt(64, 64); c.G != 255 || c.A != 255 {
+		t.Errorf("Expected opaque green at center but got %v", c)
+	}
+	// Edges of the circle are antialiased
+	partial := false
+	for x := 0; x < 64; x++ {
+		if a := img.RGBAAt(x, 19).A; a > 0 && a < 255 {
+			partial = true
+		}
+	}
+	if !partial {
+		t.Error("Expected antialiased edge on clipped circle")
+	}
+}
+
+func TestNativeConvert(t *testing.T) {
+	r, _ := New("native")
+	converted, err := r.Convert([]byte(testSvg), PNG, 200, 0)
+	if err != nil {
+		t.Fatalf("Convert failed %s", err)
+	}
+	img, err := png.Decode(bytes.NewReader(converted))
+	if err != nil {
+		t.Fatalf("Output is not a valid PNG %s", err)
+	}
+	if img.Bounds().Dx() != 200 {
+		t.Errorf("Expected width 200 but got %d", img.Bounds().Dx())
+	}
+	converted, err = r.Convert([]byte(testSvg), WEBP, 200, 0)
+	if err != nil {
+		t.Fatalf("Convert failed %s", err)
+	}
+	if !bytes.HasPrefix(converted, []byte("RIFF")) || !bytes.Equal(converted[8:12], []byte("WEBP")) {
+		t.Error("Output is not a valid WEBP")
+	}
+	converted, err = r.Convert([]byte(testSvg), JPEG, 200, 50)
+	if err != nil {
+		t.Fatalf("Convert failed %s", err)
+	}
+	img, err = jpeg.Decode(bytes.NewReader(converted))
+	if err != nil {
+		t.Fatalf("Output is not a valid JPEG %s", err)
+	}
+	// Transparent pixels are flattened onto white
+	if cr, cg, cb, _ := img.At(100, 10).RGBA(); cr>>8 < 250 || cg>>8 < 250 || cb>>8 < 250 {
+		t.Errorf("Expected white background but got %d %d %d", cr>>8, cg>>8, cb>>8)
+	}
+	converted, err = r.Convert([]byte(testSvg), AVIF, 200, 0)
+	if err != nil {
+		t.Fatalf("Convert failed %s", err)
+	}
+	if !bytes.Equal(converted[4:12], []byte("ftypavif")) {
+		t.Error("Output is not a valid AVIF")
+	}
+}
+
+func TestUnknownBackend(t *testing.T) {
+	if _, err := New("doesnotexist"); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+}
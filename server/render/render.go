@@ -0,0 +1,140 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"sort"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/gen2brain/avif"
+)
+
+// ImageFormat - raster format a natricon can be converted to
+type ImageFormat string
+
+const (
+	PNG  ImageFormat = "png"
+	WEBP ImageFormat = "webp"
+	GIF  ImageFormat = "gif"
+	APNG ImageFormat = "apng"
+	ICO  ImageFormat = "ico"
+	JPEG ImageFormat = "jpeg"
+	AVIF ImageFormat = "avif"
+)
+
+const DefaultJPEGQuality = 90 // Quality of JPEGs when none is given
+const DefaultAVIFQuality = 60 // Quality of AVIFs when none is given
+const avifSpeed = 8           // AVIF encoder speed between 0 and 10, faster produces larger files
+
+// Renderer - converts SVG output of image.CombineSVG to raster images
+type Renderer interface {
+	// Rasterize - draw the SVG on a size x size canvas
+	Rasterize(svgData []byte, size uint) (*image.RGBA, error)
+	// Convert - rasterize the SVG and encode it with the given format
+	// quality between 1 and 100 applies to lossy formats, 0 uses the default of the format
+	Convert(svgData []byte, format ImageFormat, size uint, quality int) ([]byte, error)
+	// Close - release any resources held by the backend
+	Close()
+	// Name - name of the backend, output differs between backends
+	Name() string
+}
+
+// DefaultBackend - backend used when none is specified, builds with the magick tag default to ImageMagick
+var DefaultBackend = "native"
+
+// Available backends, optional backends register themselves in init()
+var backends = map[string]func() Renderer{
+	"native": newNativeRenderer,
+}
+
+// New - create a renderer using the given backend
+func New(backend string) (Renderer, error) {
+	if backend == "" {
+		backend = DefaultBackend
+	}
+	constructor, ok := backends[backend]
+	if !ok {
+		return nil, fmt.Errorf("Unknown renderer %s, available renderers are %v", backend, Backends())
+	}
+	return constructor(), nil
+}
+
+// Backends - list of backends compiled into this binary
+func Backends() []string {
+	var ret []string
+	for name := range backends {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Lossy - whether the format takes a quality
+func (f ImageFormat) Lossy() bool {
+	return f == JPEG || f == AVIF
+}
+
+// Encode - encode a rasterized natricon with the given format
+// quality between 1 and 100 applies to lossy formats, 0 uses the default of the format
+func Encode(img image.Image, format ImageFormat, quality int) ([]byte, error) {
+	var b bytes.Buffer
+	switch format {
+	case PNG:
+		if err := png.Encode(&b, img); err != nil {
+			return nil, err
+		}
+	case WEBP:
+		if err := nativewebp.Encode(&b, img, nil); err != nil {
+			return nil, err
+		}
+	case GIF, APNG:
+		// Single frame animation
+		return EncodeAnimation([]*image.RGBA{toRGBA(img)}, []int{0}, format)
+	case ICO:
+		// Single resolution icon
+		return EncodeICO([]*image.RGBA{toRGBA(img)})
+	case JPEG:
+		if quality == 0 {
+			quality = DefaultJPEGQuality
+		}
+		// JPEGs have no alpha channel
+		if err := jpeg.Encode(&b, flatten(img, color.White), &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+	case AVIF:
+		if quality == 0 {
+			quality = DefaultAVIFQuality
+		}
+		options := avif.Options{Quality: quality, QualityAlpha: quality, Speed: avifSpeed, ChromaSubsampling: image.YCbCrSubsampleRatio420}
+		if err := avif.Encode(&b, img, options); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("Unsupported image format")
+	}
+	return b.Bytes(), nil
+}
+
+// flatten - draw image over an opaque background
+func flatten(img image.Image, background color.Color) *image.RGBA {
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flat
+}
+
+// toRGBA - get image as RGBA, converting it if necessary
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
@@ -18,8 +18,9 @@ const (
 // Vanity
 type Vanity struct {
 	// Optional fields
-	Hash  string    // Will generate the natricon with specific hash
-	Badge BadgeType // Will generate natricon with specified badge
+	Hash        string    // Will generate the natricon with specific hash
+	Badge       BadgeType // Will generate natricon with specified badge
+	Accessories []string  // Accessories worn unless the request picks its own
 	// If using any of the below then ALL of them are required
 	BodyColor    *color.RGB
 	HairColor    *color.RGB